@@ -3,8 +3,8 @@ package main
 import (
 	"context"
 	"fmt"
-	"time"
 
+	"github.com/shinzonetwork/app-sdk/pkg/app"
 	"github.com/shinzonetwork/app-sdk/pkg/defra"
 	"github.com/shinzonetwork/app-sdk/pkg/networking"
 )
@@ -20,13 +20,14 @@ func main() {
 	cfg.DefraDB.Store.Path = "./.defra"
 	cfg.DefraDB.Url = defraUrl
 	cfg.DefraDB.P2P.ListenAddr = listenAddress
-	myNode, err := defra.StartDefraInstance(cfg, &defra.MockSchemaApplierThatSucceeds{})
+	cfg.DefraDB.P2P.MDNS.Enabled = true // Let the other example apps find this node on the local network
+	myApp, err := app.New(cfg, &defra.MockSchemaApplierThatSucceeds{})
 	if err != nil {
 		panic(err)
 	}
-	defer myNode.Close(context.Background())
 
-	for {
-		time.Sleep(1 * time.Second) // Run until cancelled
+	// Run until interrupted, then close the node on the way out
+	if err := myApp.Run(context.Background()); err != nil {
+		panic(err)
 	}
 }
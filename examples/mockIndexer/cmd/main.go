@@ -34,7 +34,7 @@ func main() {
 	cfg.DefraDB.Store.Path = "./.defra"
 	cfg.DefraDB.Url = defraUrl
 	cfg.DefraDB.P2P.ListenAddr = listenAddress
-	cfg.DefraDB.P2P.BootstrapPeers = append(defra.DefaultConfig.DefraDB.P2P.BootstrapPeers, "/ip4/192.168.4.22/tcp/9176/p2p/12D3KooWLttXvtbokAphdVWL6hx7VEviDnHYwQs5SmAw1Y1yfcZT")
+	cfg.DefraDB.P2P.MDNS.Enabled = true // Find the other example apps on the local network
 	cfg.Logger.Development = false
 
 	log.Println("⏳ Starting DefraDB instance...")
@@ -175,10 +175,12 @@ func TestSyncFromMultipleWritersWithSomeOverlappingData(t *testing.T) {
 
 	// Write data to each writer
 	type UserResult struct {
-		Name    string   `json:"name"`
-		Friends []string `json:"friends"`
+		Name    string                `json:"name"`
+		Friends []string              `json:"friends"`
+		Version []attestation.Version `json:"_version"`
 	}
 
+	writtenCIDs := []string{}
 	for i, writer := range writerDefras {
 		var friendsStr string
 		var friends []string
@@ -204,16 +206,26 @@ func TestSyncFromMultipleWritersWithSomeOverlappingData(t *testing.T) {
 			create_User(input: { name: "Quinn", friends: %s }) {
 				name
 				friends
+				_version {
+					cid
+				}
 			}
 		}`, friendsStr)
 
 		result, err := defra.PostMutation[UserResult](ctx, writer, mutation)
 		require.NoError(t, err)
 		require.Equal(t, "Quinn", result.Name)
+		for _, version := range result.Version {
+			writtenCIDs = append(writtenCIDs, version.CID)
+		}
 	}
 
-	// Wait for sync
-	time.Sleep(5 * time.Second)
+	// Wait for every writer's commit to sync to the reader
+	syncCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	for _, writtenCID := range writtenCIDs {
+		require.NoError(t, defra.WaitForCommit(syncCtx, readerDefra, writtenCID))
+	}
 
 	// Query all User entries to see how DefraDB handles the conflicting data
 	query := `query {
@@ -250,6 +262,24 @@ func TestSyncFromMultipleWritersWithSomeOverlappingData(t *testing.T) {
 			t.Fatalf("Unexpected user object %+v", user)
 		}
 	}
+
+	// Both docs describe the same logical user - resolve them to a single answer, keyed by name
+	err = attestation.AddAttestationRecordCollection(ctx, readerDefra, "User")
+	require.NoError(t, err)
+	consensusQuery := `query {
+		User {
+			_docID
+			name
+			friends
+		}
+	}`
+	result, err := attestation.QuerySingleByConsensus[UserResult](ctx, readerDefra, "User", consensusQuery, "name", "Quinn")
+	require.NoError(t, err)
+	require.Equal(t, standardFriends, result.Winner.Doc.Friends)
+	require.Equal(t, defraNodes-1, result.Winner.Attestations())
+	require.Len(t, result.Losers, 1)
+	require.Equal(t, modifiedFriends, result.Losers[0].Doc.Friends)
+	require.False(t, result.Contested)
 }
 
 // This test mimics TestSyncFromMultipleWritersWithSomeOverlappingData but adds in a vote_count [GCounter CRDT](https://github.com/sourcenetwork/defradb/tree/develop/internal/core/crdt#gcounter---increment-only-counter)
@@ -778,7 +808,7 @@ func TestSyncFromMultipleWritersWithOneMaliciouslyOverwritingDataButThenRecoverV
 
 	// Now, let's try to recover our data integrity via timetravel queries
 	user := users[0]
-	require.True(t, hasDocBeenModified(user.Version))
+	require.True(t, attestation.HasDocBeenModified(user.Version))
 	// Extract CIDs with height of 1 (i.e. original versions of the doc)
 	originalVersionCIDs := []string{}
 	for _, version := range user.Version {
@@ -803,13 +833,10 @@ func TestSyncFromMultipleWritersWithOneMaliciouslyOverwritingDataButThenRecoverV
 		require.Equal(t, "Quinn", originalUserEntry.Name)
 		require.ElementsMatch(t, standardFriends, originalUserEntry.Friends)
 	}
-}
 
-func hasDocBeenModified(versions []attestation.Version) bool {
-	for _, version := range versions {
-		if version.Height > uint(1) {
-			return true
-		}
-	}
-	return false
+	// The same recovery is available as a supported API: the malicious writer's updates are only signed by one identity
+	lastKnownGood, err := attestation.QueryLastKnownGood[UserResult](ctx, readerDefra, "User", user.DocId, "name friends", 2, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Quinn", lastKnownGood.Name)
+	require.ElementsMatch(t, standardFriends, lastKnownGood.Friends)
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/shinzonetwork/app-sdk/pkg/defra"
 	"github.com/shinzonetwork/app-sdk/pkg/logger"
+	"github.com/shinzonetwork/app-sdk/pkg/networking"
 	"github.com/sourcenetwork/defradb/http"
 	"github.com/sourcenetwork/defradb/node"
 	"github.com/sourcenetwork/go-p2p"
@@ -361,7 +362,7 @@ func assertDefraInstanceDoesNotHaveData(t *testing.T, ctx context.Context, reade
 // With the "big peer" not subscribed to the "User" collection, data is not passively replicated to it
 // We see that the data is able to "hop" past the "big peer" and make it to our reader nodes
 func TestMultiTenantP2PReplication_ConnectToBigPeerWhoDoesNotDeclareInterestInTopics(t *testing.T) {
-	ipAddress, err := getLANIP() // Must use external address like IP address instead of loop back address for this to work - otherwise we will not hop past our big peer
+	ipAddress, err := networking.GetLANIP() // Must use external address like IP address instead of loop back address for this to work - otherwise we will not hop past our big peer
 	require.NoError(t, err)
 	listenAddress := fmt.Sprintf("/ip4/%s/tcp/0", ipAddress)
 	defraUrl := fmt.Sprintf("%s:0", ipAddress)
@@ -1,18 +0,0 @@
-package experiments
-
-import (
-	"fmt"
-	"net"
-)
-
-func getLANIP() (string, error) {
-	conn, err := net.Dial("udp", "8.8.8.8:80")
-	if err != nil {
-		return "", fmt.Errorf("Error retrieving ip address: %v", err)
-	}
-	defer conn.Close()
-
-	localAddr := conn.LocalAddr().(*net.UDPAddr)
-
-	return localAddr.IP.String(), nil
-}
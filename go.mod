@@ -3,15 +3,25 @@ module github.com/shinzonetwork/app-sdk
 go 1.25.4
 
 require (
+	github.com/ipfs/go-cid v0.5.0
+	github.com/ipld/go-ipld-prime v0.21.0
 	github.com/joho/godotenv v1.5.1
 	github.com/libp2p/go-libp2p v0.43.0
+	github.com/libp2p/zeroconf/v2 v2.2.0
+	github.com/multiformats/go-multiaddr v0.16.1
 	github.com/shinzonetwork/indexer v0.1.1-0.20251120164521-e7d20c7b0344
 	github.com/shinzonetwork/shinzo-host-client v0.0.0-20251105152353-1066c5154025
 	github.com/shinzonetwork/view-creator v0.0.0-20251113191457-a28acb09bf07
+	github.com/sourcenetwork/corekv v0.2.4
+	github.com/sourcenetwork/corekv/blockstore v0.2.4
+	github.com/sourcenetwork/corekv/namespace v0.2.4
 	github.com/sourcenetwork/defradb v0.20.0
 	github.com/sourcenetwork/go-p2p v0.1.4
+	github.com/sourcenetwork/graphql-go v0.7.10-0.20241003221550-224346887b4a
+	github.com/sourcenetwork/immutable v0.3.0
 	github.com/stretchr/testify v1.11.1
 	go.uber.org/zap v1.27.0
+	golang.org/x/sync v0.17.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -189,7 +199,6 @@ require (
 	github.com/ipfs/bbloom v0.0.4 // indirect
 	github.com/ipfs/boxo v0.35.0 // indirect
 	github.com/ipfs/go-block-format v0.2.3 // indirect
-	github.com/ipfs/go-cid v0.5.0 // indirect
 	github.com/ipfs/go-cidutil v0.1.0 // indirect
 	github.com/ipfs/go-datastore v0.9.0 // indirect
 	github.com/ipfs/go-dsqueue v0.0.5 // indirect
@@ -198,7 +207,6 @@ require (
 	github.com/ipfs/go-log/v2 v2.8.1 // indirect
 	github.com/ipfs/go-metrics-interface v0.3.0 // indirect
 	github.com/ipfs/go-peertaskqueue v0.8.2 // indirect
-	github.com/ipld/go-ipld-prime v0.21.0 // indirect
 	github.com/ipld/go-ipld-prime/storage/bsadapter v0.0.0-20250821084354-a425e60cd714 // indirect
 	github.com/ipld/go-ipld-prime/storage/bsrvadapter v0.0.0-20250821084354-a425e60cd714 // indirect
 	github.com/jackpal/go-nat-pmp v1.0.2 // indirect
@@ -253,7 +261,6 @@ require (
 	github.com/mtibben/percent v0.2.1 // indirect
 	github.com/multiformats/go-base32 v0.1.0 // indirect
 	github.com/multiformats/go-base36 v0.2.0 // indirect
-	github.com/multiformats/go-multiaddr v0.16.1 // indirect
 	github.com/multiformats/go-multiaddr-dns v0.4.1 // indirect
 	github.com/multiformats/go-multiaddr-fmt v0.1.0 // indirect
 	github.com/multiformats/go-multibase v0.2.0 // indirect
@@ -313,17 +320,12 @@ require (
 	github.com/sirupsen/logrus v1.9.3 // indirect
 	github.com/sourcegraph/conc v0.3.0 // indirect
 	github.com/sourcenetwork/acp_core v0.4.1 // indirect
-	github.com/sourcenetwork/corekv v0.2.4 // indirect
 	github.com/sourcenetwork/corekv/badger v0.2.4 // indirect
-	github.com/sourcenetwork/corekv/blockstore v0.2.4 // indirect
 	github.com/sourcenetwork/corekv/chunk v0.2.4 // indirect
 	github.com/sourcenetwork/corekv/memory v0.2.4 // indirect
-	github.com/sourcenetwork/corekv/namespace v0.2.4 // indirect
 	github.com/sourcenetwork/corelog v0.0.8 // indirect
 	github.com/sourcenetwork/go-libp2p-pubsub-rpc v0.0.14 // indirect
 	github.com/sourcenetwork/goji v0.0.8 // indirect
-	github.com/sourcenetwork/graphql-go v0.7.10-0.20241003221550-224346887b4a // indirect
-	github.com/sourcenetwork/immutable v0.3.0 // indirect
 	github.com/sourcenetwork/lens/host-go v0.9.4 // indirect
 	github.com/sourcenetwork/raccoondb v0.2.1-0.20240722161350-d4a78b691ec8 // indirect
 	github.com/sourcenetwork/raccoondb/v2 v2.0.0 // indirect
@@ -382,7 +384,6 @@ require (
 	golang.org/x/mod v0.28.0 // indirect
 	golang.org/x/net v0.44.0 // indirect
 	golang.org/x/oauth2 v0.31.0 // indirect
-	golang.org/x/sync v0.17.0 // indirect
 	golang.org/x/sys v0.36.0 // indirect
 	golang.org/x/telemetry v0.0.0-20250908211612-aef8a434d053 // indirect
 	golang.org/x/term v0.35.0 // indirect
@@ -0,0 +1,359 @@
+// Package app bundles a defra node with the config, identity and logger it was started with,
+// so that an application can query, write, sign, subscribe and attest through a single handle.
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/shinzonetwork/app-sdk/pkg/attestation"
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/shinzonetwork/app-sdk/pkg/logger"
+	"github.com/shinzonetwork/app-sdk/pkg/signer"
+	"github.com/shinzonetwork/app-sdk/pkg/views"
+	"github.com/sourcenetwork/defradb/acp/identity"
+	"github.com/sourcenetwork/defradb/node"
+	"go.uber.org/zap"
+)
+
+// DefaultShutdownTimeout bounds how long Run waits for in-flight writes to finish before closing the node
+const DefaultShutdownTimeout = 30 * time.Second
+
+var ErrClosed = errors.New("app is closed")
+
+var errNoIdentity = fmt.Errorf("no local identity to sign with: %w", defra.ErrNotSupportedRemotely)
+
+// App owns a running defra node along with its config, identity and logger
+type App struct {
+	node     *defra.Node
+	config   *config.Config
+	identity identity.FullIdentity
+	logger   *zap.SugaredLogger
+	trusted  *attestation.TrustedSigners
+
+	// closing is set once Close has been called; writes started after that are rejected
+	mu      sync.RWMutex
+	closing bool
+	writes  sync.WaitGroup
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// New starts a defra instance (see defra.StartDefraInstance) and wraps it in an App
+func New(cfg *config.Config, schemaApplier defra.SchemaApplier, collectionsOfInterest ...string) (*App, error) {
+	return Start(context.Background(), cfg, schemaApplier, collectionsOfInterest...)
+}
+
+// Start starts a defra instance (see defra.StartNode) and wraps it in an App.
+// Startup is abandoned if ctx is cancelled, and nothing is left running if Start returns an error.
+func Start(ctx context.Context, cfg *config.Config, schemaApplier defra.SchemaApplier, collectionsOfInterest ...string) (*App, error) {
+	defraNode, err := defra.StartNode(ctx, cfg, schemaApplier, collectionsOfInterest...)
+	if err != nil {
+		return nil, err
+	}
+	return wrapStartedNode(defraNode, cfg)
+}
+
+// NewWithTestConfig is a simple wrapper on New that starts the defra instance with defra.StartNodeWithTestConfig
+func NewWithTestConfig(t *testing.T, cfg *config.Config, schemaApplier defra.SchemaApplier, collectionsOfInterest ...string) (*App, error) {
+	if cfg == nil {
+		cfg = defra.DefaultConfig
+	}
+	defraNode, err := defra.StartNodeWithTestConfig(t, cfg, schemaApplier, collectionsOfInterest...)
+	if err != nil {
+		return nil, err
+	}
+	return wrapStartedNode(defraNode, cfg)
+}
+
+// Wrap builds an App around a defra node that has already been started with the given config.
+// The App takes ownership of the node; closing the App closes the node. Peer state such as BootstrapPeerStatus is
+// only available for nodes started by the App itself.
+func Wrap(defraNode *node.Node, cfg *config.Config) (*App, error) {
+	if defraNode == nil {
+		return nil, fmt.Errorf("defra node cannot be nil")
+	}
+	return wrap(&defra.Node{Node: defraNode}, cfg)
+}
+
+// wrap builds an App around a started defra.Node
+func wrap(defraNode *defra.Node, cfg *config.Config) (*App, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	if logger.Sugar == nil {
+		logger.Init(cfg.Logger.Development)
+	}
+
+	// A remote DefraDB's keys live with the remote process, so there is no local identity to sign with
+	var fullIdentity identity.FullIdentity
+	if !defra.IsRemote(defraNode.Node) {
+		var err error
+		fullIdentity, err = signer.LoadNodeIdentity(defraNode, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	trusted, err := attestation.LoadTrustedSignersFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &App{
+		node:     defraNode,
+		config:   cfg,
+		identity: fullIdentity,
+		logger:   logger.Sugar,
+		trusted:  trusted,
+	}, nil
+}
+
+// wrapStartedNode wraps a node started on the App's behalf, closing it again if the App can't be built
+func wrapStartedNode(defraNode *defra.Node, cfg *config.Config) (*App, error) {
+	app, err := wrap(defraNode, cfg)
+	if err != nil {
+		defraNode.Close(context.Background())
+		return nil, err
+	}
+	return app, nil
+}
+
+// Node returns the underlying defra node, for anything the App does not expose directly
+func (a *App) Node() *node.Node {
+	return a.node.Node
+}
+
+// Config returns the config the App was started with
+func (a *App) Config() *config.Config {
+	return a.config
+}
+
+// Identity returns the node's DefraDB identity, loaded once when the App was created. It is nil for a remote DefraDB.
+func (a *App) Identity() identity.FullIdentity {
+	return a.identity
+}
+
+// Logger returns the App's logger
+func (a *App) Logger() *zap.SugaredLogger {
+	return a.logger
+}
+
+// BootstrapPeerStatus returns the connection status of each configured bootstrap peer (see defra.Node.BootstrapPeerStatus)
+func (a *App) BootstrapPeerStatus() ([]defra.PeerStatus, error) {
+	return a.node.BootstrapPeerStatus()
+}
+
+// PeerBook returns the peers remembered across restarts (see defra.Node.PeerBook)
+func (a *App) PeerBook() (*defra.PeerBook, error) {
+	return a.node.PeerBook()
+}
+
+// DiscoveredPeers returns the peers found on the local network over mDNS (see defra.Node.DiscoveredPeers)
+func (a *App) DiscoveredPeers() ([]defra.DiscoveredPeer, error) {
+	return a.node.DiscoveredPeers()
+}
+
+// PeerFilter returns the rules deciding which peers the node talks to, which can be changed at runtime (see defra.Node.PeerFilter)
+func (a *App) PeerFilter() (*defra.PeerFilter, error) {
+	return a.node.PeerFilter()
+}
+
+// TrustedSigners returns the allowlist loaded from the config's shinzo section (nil if none is configured, trusting every identity)
+func (a *App) TrustedSigners() *attestation.TrustedSigners {
+	return a.trusted
+}
+
+// Query executes a GraphQL query and unmarshals the result into result, which must be a pointer to a struct or a slice.
+// Optional variables are passed to DefraDB separately from the query (see defra.QueryArrayWithVariables).
+func (a *App) Query(ctx context.Context, query string, result any, variables ...map[string]any) error {
+	vars := map[string]any{}
+	for _, v := range variables {
+		for key, value := range v {
+			vars[key] = value
+		}
+	}
+	data, err := defra.QueryArrayWithVariables[json.RawMessage](ctx, a.node.Node, query, vars)
+	if err != nil {
+		return err
+	}
+	return unmarshalResults(data, result)
+}
+
+// QueryAttested behaves like Query against a View, but drops any document that doesn't meet the configured
+// `shinzo.minimum_attestations` from the configured trusted signers (see attestation.QueryArrayWithTrustedAttestations)
+func (a *App) QueryAttested(ctx context.Context, viewName string, query string, result any) error {
+	minimumAttestations, err := a.config.Shinzo.GetMinimumAttestations()
+	if err != nil {
+		return err
+	}
+	data, err := attestation.QueryArrayWithTrustedAttestations[json.RawMessage](ctx, a.node.Node, viewName, query, minimumAttestations, a.trusted)
+	if err != nil {
+		return err
+	}
+	return unmarshalResults(data, result)
+}
+
+// Mutate executes a GraphQL mutation and unmarshals the first resulting document into result
+func (a *App) Mutate(ctx context.Context, mutation string, result any) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.writes.Done()
+
+	data, err := defra.PostMutation[json.RawMessage](ctx, a.node.Node, mutation)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(*data, result); err != nil {
+		return fmt.Errorf("failed to unmarshal mutation result: %w", err)
+	}
+	return nil
+}
+
+// Sign signs a message with the node's DefraDB identity (secp256k1), returning a hex-encoded signature
+func (a *App) Sign(message string) (string, error) {
+	if a.identity == nil {
+		return "", errNoIdentity
+	}
+	return signer.SignWithIdentity(message, a.identity)
+}
+
+// SignP2P signs a message with the node's LibP2P key (Ed25519), returning a hex-encoded signature
+func (a *App) SignP2P(message string) (string, error) {
+	if a.identity == nil {
+		return "", errNoIdentity
+	}
+	return signer.SignWithP2PIdentity(message, a.identity)
+}
+
+// PublicKey returns the hex-encoded public key of the node's DefraDB identity, as it appears in `_version` signatures
+func (a *App) PublicKey() (string, error) {
+	if a.identity == nil {
+		return "", errNoIdentity
+	}
+	return signer.GetIdentityPublicKey(a.identity)
+}
+
+// Subscribe applies a View's schema and subscribes to its documents over P2P
+func (a *App) Subscribe(ctx context.Context, view *views.View) error {
+	return view.SubscribeTo(ctx, a.node.Node)
+}
+
+// SubscribeToCollections subscribes to already defined collections over P2P
+func (a *App) SubscribeToCollections(ctx context.Context, collectionNames ...string) error {
+	err := a.node.DB.AddP2PCollections(ctx, collectionNames...)
+	if err != nil {
+		return fmt.Errorf("Error subscribing to collections %v: %w", collectionNames, err)
+	}
+	return nil
+}
+
+// Attest writes (or extends) the AttestationRecords for a View doc, attesting to the given source docs (see attestation.WriteAttestationRecord)
+func (a *App) Attest(ctx context.Context, viewName string, viewDocId string, sources ...attestation.SourceDoc) ([]attestation.AttestationRecord, error) {
+	if err := a.beginWrite(); err != nil {
+		return nil, err
+	}
+	defer a.writes.Done()
+
+	return attestation.WriteAttestationRecord(ctx, a.node.Node, viewName, viewDocId, sources...)
+}
+
+// AttestationReport summarises the attestation health of a View against the configured trusted signers (see attestation.Report)
+func (a *App) AttestationReport(ctx context.Context, viewName string) (*attestation.ViewReport, error) {
+	return attestation.Report(ctx, a.node.Node, viewName, attestation.WithTrustedSigners(a.trusted))
+}
+
+// Run blocks until ctx is cancelled or the process receives SIGINT or SIGTERM, then shuts the App down,
+// giving in-flight writes up to DefaultShutdownTimeout to finish before the node is closed.
+func (a *App) Run(ctx context.Context) error {
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	<-signalCtx.Done()
+	a.logger.Info("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+	defer cancel()
+	return a.Close(shutdownCtx)
+}
+
+// Close stops accepting writes, waits for in-flight writes to finish (or ctx to be done) and shuts down the defra node.
+// It is safe to call more than once.
+func (a *App) Close(ctx context.Context) error {
+	a.closeOnce.Do(func() {
+		a.mu.Lock()
+		a.closing = true
+		a.mu.Unlock()
+
+		drained := make(chan struct{})
+		go func() {
+			a.writes.Wait()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			a.logger.Warnf("Closing defra node with writes still in flight: %v", ctx.Err())
+		}
+
+		// ctx may be done by now, but the node must still be closed
+		a.closeErr = a.node.Close(context.WithoutCancel(ctx))
+	})
+	return a.closeErr
+}
+
+// beginWrite registers an in-flight write, failing if the App is closing. Callers must call a.writes.Done() when the write completes.
+func (a *App) beginWrite() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closing {
+		return ErrClosed
+	}
+	a.writes.Add(1)
+	return nil
+}
+
+// unmarshalResults decodes query results into result: the full list for a slice pointer, otherwise the first result
+func unmarshalResults(data []json.RawMessage, result any) error {
+	if result == nil {
+		return nil
+	}
+
+	var encoded []byte
+	var err error
+	if isSlicePointer(result) {
+		encoded, err = json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal query results: %w", err)
+		}
+	} else {
+		if len(data) == 0 {
+			return fmt.Errorf("query returned no results")
+		}
+		encoded = data[0]
+	}
+
+	if err := json.Unmarshal(encoded, result); err != nil {
+		return fmt.Errorf("failed to unmarshal query results: %w", err)
+	}
+	return nil
+}
+
+func isSlicePointer(result any) bool {
+	value := reflect.ValueOf(result)
+	return value.Kind() == reflect.Ptr && value.Elem().Kind() == reflect.Slice
+}
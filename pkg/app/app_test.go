@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shinzonetwork/app-sdk/pkg/attestation"
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/shinzonetwork/app-sdk/pkg/signer"
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	DocId string `json:"_docID"`
+	Name  string `json:"name"`
+}
+
+func TestApp(t *testing.T) {
+	ctx := t.Context()
+	app, err := NewWithTestConfig(t, nil, defra.NewSchemaApplierFromProvidedSchema("type User { name: String }"))
+	require.NoError(t, err)
+	defer app.Close(ctx)
+	require.NotNil(t, app.Node())
+	require.NotNil(t, app.Config())
+	require.NotNil(t, app.Logger())
+
+	var created user
+	err = app.Mutate(ctx, `mutation { create_User(input: { name: "Quinn" }) { _docID name } }`, &created)
+	require.NoError(t, err)
+	require.Equal(t, "Quinn", created.Name)
+
+	var users []user
+	err = app.Query(ctx, `User { _docID name }`, &users)
+	require.NoError(t, err)
+	require.Equal(t, []user{created}, users)
+
+	var single user
+	err = app.Query(ctx, `query($docId: String) { User(docID: $docId) { _docID name } }`, &single, map[string]any{"docId": created.DocId})
+	require.NoError(t, err)
+	require.Equal(t, created, single)
+
+	// Signatures are made with the identity the node signs its commits with
+	publicKey, err := app.PublicKey()
+	require.NoError(t, err)
+	var versions []struct {
+		Version []attestation.Version `json:"_version"`
+	}
+	err = app.Query(ctx, fmt.Sprintf(`User(docID: "%s") { _version { signature { identity } } }`, created.DocId), &versions)
+	require.NoError(t, err)
+	require.Equal(t, publicKey, versions[0].Version[0].Signature.Identity)
+
+	signature, err := app.Sign("hello")
+	require.NoError(t, err)
+	require.NoError(t, signer.VerifyDefraSignature(publicKey, "hello", signature))
+
+	require.NoError(t, app.Close(ctx))
+	require.NoError(t, app.Close(ctx))
+}
+
+func TestRunClosesOnCancel(t *testing.T) {
+	app, err := NewWithTestConfig(t, nil, defra.NewSchemaApplierFromProvidedSchema("type User { name: String }"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- app.Run(ctx)
+	}()
+
+	err = app.Mutate(t.Context(), `mutation { create_User(input: { name: "Quinn" }) { _docID } }`, nil)
+	require.NoError(t, err)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(DefaultShutdownTimeout):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+
+	// Writes are rejected once the App has shut down
+	err = app.Mutate(t.Context(), `mutation { create_User(input: { name: "Late" }) { _docID } }`, nil)
+	require.ErrorIs(t, err, ErrClosed)
+}
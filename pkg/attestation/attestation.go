@@ -3,26 +3,21 @@ package attestation
 import (
 	"context"
 	"fmt"
-	"regexp"
-	"strings"
+	"sync"
 
 	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/shinzonetwork/app-sdk/pkg/sdl"
 	"github.com/shinzonetwork/indexer/pkg/schema"
 	"github.com/sourcenetwork/defradb/node"
+	"golang.org/x/sync/errgroup"
 )
 
 func getAttestationRecordSDL(viewName string) string {
-	primitiveSchema := schema.GetSchema()
-	primitives, err := extractSchemaTypes(primitiveSchema)
-	if err == nil {
-		for _, primitive := range primitives {
-			if viewName == primitive { // For our primitive attestation records, we use a condensed schema
-				return fmt.Sprintf(`type AttestationRecord_%s { 
-					attested_doc: String
-					CIDs: [String]
-				}`, viewName)
-			}
-		}
+	if isPrimitive(viewName) { // For our primitive attestation records, we use a condensed schema
+		return fmt.Sprintf(`type AttestationRecord_%s { 
+			attested_doc: String
+			CIDs: [String]
+		}`, viewName)
 	}
 
 	// If either AttestationRecord does not have unique name, we will get an error when trying to the schema (collection already exists error)
@@ -40,6 +35,15 @@ type AttestationRecord struct {
 	CIDs          []string `json:"CIDs"`
 }
 
+// sourceDocId returns the doc whose commits the record lists. Primitive records have no source_doc, as they attest to
+// the doc they were built from.
+func (r AttestationRecord) sourceDocId() string {
+	if r.SourceDocId == "" {
+		return r.AttestedDocId
+	}
+	return r.SourceDocId
+}
+
 func AddAttestationRecordCollection(ctx context.Context, defraNode *node.Node, associatedViewName string) error {
 	collectionSDL := getAttestationRecordSDL(associatedViewName)
 	schemaApplier := defra.NewSchemaApplierFromProvidedSchema(collectionSDL)
@@ -56,48 +60,160 @@ func AddAttestationRecordCollection(ctx context.Context, defraNode *node.Node, a
 	return nil
 }
 
-func GetAttestationRecords(ctx context.Context, defraNode *node.Node, associatedViewName string, viewDocIds []string) ([]AttestationRecord, error) {
-	// Build a comma-separated list of quoted doc IDs for GraphQL _in filter
-	quoted := make([]string, 0, len(viewDocIds))
-	for _, id := range viewDocIds {
-		quoted = append(quoted, fmt.Sprintf("\"%s\"", id))
-	}
-	inList := strings.Join(quoted, ", ")
-
-	query := fmt.Sprintf(`query {
-        AttestationRecord_%s (filter: {attested_doc: {_in: [%s]}}) {
-            attested_doc
-            source_doc
-            CIDs
-        }
-    }`, associatedViewName, inList)
-	records, err := defra.QueryArray[AttestationRecord](ctx, defraNode, query)
-	if err != nil {
-		return nil, fmt.Errorf("Error fetching attestation record: %w", err)
+const (
+	DefaultAttestationRecordBatchSize   = 500
+	DefaultAttestationRecordConcurrency = 4
+)
+
+// Option configures how attestation data is fetched and evaluated
+type Option func(*options)
+
+type options struct {
+	batchSize   int
+	concurrency int
+	trusted     *TrustedSigners
+}
+
+func newOptions(opts ...Option) options {
+	o := options{
+		batchSize:   DefaultAttestationRecordBatchSize,
+		concurrency: DefaultAttestationRecordConcurrency,
 	}
-	if len(records) == 0 {
-		return nil, fmt.Errorf("No attestation records found with query: %s", query)
+	for _, opt := range opts {
+		opt(&o)
 	}
-	return records, nil
+	return o
 }
 
-// extractSchemaTypes extracts all type names from a GraphQL SDL schema
-func extractSchemaTypes(schema string) ([]string, error) {
-	// Find all type definitions: type TypeName { ... }
-	re := regexp.MustCompile(`type\s+(\w+)\s*@?[^{]*\{`)
-	matches := re.FindAllStringSubmatch(schema, -1)
+// WithBatchSize sets the maximum number of doc IDs or CIDs sent in a single attestation query
+func WithBatchSize(batchSize int) Option {
+	return func(o *options) {
+		if batchSize > 0 {
+			o.batchSize = batchSize
+		}
+	}
+}
+
+// WithConcurrency sets the maximum number of attestation queries in flight at once
+func WithConcurrency(concurrency int) Option {
+	return func(o *options) {
+		if concurrency > 0 {
+			o.concurrency = concurrency
+		}
+	}
+}
+
+// WithTrustedSigners only counts signatures by identities in the given allowlist as trusted. A nil allowlist trusts every identity.
+func WithTrustedSigners(trusted *TrustedSigners) Option {
+	return func(o *options) {
+		o.trusted = trusted
+	}
+}
 
-	if len(matches) == 0 {
-		return nil, fmt.Errorf("no type definitions found in schema")
+// GetAttestationRecords fetches the attestation records for the given View docs, keyed by attested_doc.
+// Every requested doc ID is present in the result; an empty slice means the doc has no attestations,
+// whereas an error means the records could not be fetched.
+func GetAttestationRecords(ctx context.Context, defraNode *node.Node, associatedViewName string, viewDocIds []string, opts ...Option) (map[string][]AttestationRecord, error) {
+	records, err := queryAttestationRecords(ctx, defraNode, associatedViewName, viewDocIds, opts...)
+	if err != nil {
+		return nil, err
 	}
 
-	types := make([]string, 0, len(matches))
-	for _, match := range matches {
-		if len(match) > 1 {
-			typeName := strings.TrimSpace(match[1])
-			types = append(types, typeName)
+	recordsByDoc := make(map[string][]AttestationRecord, len(viewDocIds))
+	for _, id := range viewDocIds {
+		recordsByDoc[id] = []AttestationRecord{}
+	}
+	for _, record := range records {
+		if _, requested := recordsByDoc[record.AttestedDocId]; requested {
+			recordsByDoc[record.AttestedDocId] = append(recordsByDoc[record.AttestedDocId], record)
+		}
+	}
+	return recordsByDoc, nil
+}
+
+// queryAttestationRecords fetches the attestation records for the given view docs in batches, fanning the batches out concurrently.
+// Returns an empty slice (not an error) when none exist.
+func queryAttestationRecords(ctx context.Context, defraNode *node.Node, associatedViewName string, viewDocIds []string, opts ...Option) ([]AttestationRecord, error) {
+	query := getAttestationRecordQuery(associatedViewName)
+	return queryInBatches(ctx, viewDocIds, newOptions(opts...), func(ctx context.Context, batch []any) ([]AttestationRecord, error) {
+		records, err := defra.QueryArrayWithVariables[AttestationRecord](ctx, defraNode, query, map[string]any{"docIds": batch})
+		if err != nil {
+			return nil, fmt.Errorf("Error fetching attestation records for %s: %w", associatedViewName, err)
+		}
+		return records, nil
+	})
+}
+
+// queryInBatches runs fetch on batches of at most options.batchSize of the given IDs, with up to options.concurrency
+// batches in flight at once, and returns the results of every batch in order. IDs are de-duplicated first, so that
+// nothing is fetched (and returned) more than once.
+func queryInBatches[T any](ctx context.Context, ids []string, options options, fetch func(ctx context.Context, batch []any) ([]T, error)) ([]T, error) {
+	seen := make(map[string]struct{}, len(ids))
+	unique := make([]any, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
 		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	if len(unique) == 0 {
+		return []T{}, nil
 	}
 
-	return types, nil
+	batchCount := (len(unique) + options.batchSize - 1) / options.batchSize
+	batches := make([][]T, batchCount)
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(options.concurrency)
+	for i := 0; i < batchCount; i++ {
+		batch := unique[i*options.batchSize : min((i+1)*options.batchSize, len(unique))]
+		group.Go(func() error {
+			results, err := fetch(groupCtx, batch)
+			if err != nil {
+				return err
+			}
+			batches[i] = results
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	results := []T{}
+	for _, batch := range batches {
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+// getAttestationRecordQuery returns the parameterised query for a View's attestation records, taking the `$docIds` variable
+func getAttestationRecordQuery(associatedViewName string) string {
+	// Condensed primitive attestation records have no source_doc field
+	sourceDocField := "source_doc"
+	if isPrimitive(associatedViewName) {
+		sourceDocField = ""
+	}
+	return fmt.Sprintf(`query($docIds: [String]) {
+		AttestationRecord_%s (filter: {attested_doc: {_in: $docIds}}) {
+			attested_doc
+			%s
+			CIDs
+		}
+	}`, associatedViewName, sourceDocField)
+}
+
+// primitiveSchema is the indexer's primitive schema, parsed once on first use
+var primitiveSchema = sync.OnceValues(func() (*sdl.Schema, error) {
+	return sdl.Parse(schema.GetSchema())
+})
+
+// isPrimitive reports whether the given name is one of the indexer's primitive collections (e.g. Block, Transaction)
+func isPrimitive(collectionName string) bool {
+	primitives, err := primitiveSchema()
+	if err != nil {
+		return false
+	}
+	definition := primitives.Type(collectionName)
+	return definition != nil && definition.Kind == sdl.KindObject
 }
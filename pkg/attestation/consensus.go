@@ -0,0 +1,145 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// Candidate is one of the docs competing to represent a natural key
+type Candidate[T any] struct {
+	DocId string
+	Doc   T
+	// Signers holds the distinct trusted identities that attested to the doc, sorted
+	Signers []string
+}
+
+// Attestations returns the candidate's score: the number of distinct trusted identities that attested to it
+func (c Candidate[T]) Attestations() int {
+	return len(c.Signers)
+}
+
+// ConsensusResult is the outcome of a majority-consensus read for a single natural key
+type ConsensusResult[T any] struct {
+	Key    string
+	Winner Candidate[T]
+	// Losers holds the remaining candidates for the key, best scoring first
+	Losers []Candidate[T]
+	// Contested is true when the runner-up has as many attestations as the winner, in which case the winner is
+	// only picked deterministically (by lowest doc ID) and should not be relied upon
+	Contested bool
+}
+
+// QueryArrayByConsensus executes a GraphQL query against a View and groups the returned docs by the value of keyField,
+// a natural key such as a block or transaction hash. Honest and malicious writers create separate docs for the same
+// entity, so each group is resolved to the candidate attested by the most distinct trusted identities (see WithTrustedSigners).
+// The query must select `_docID` and keyField on the View's documents. Results are sorted by key.
+func QueryArrayByConsensus[T any](ctx context.Context, defraNode *node.Node, viewName string, query string, keyField string, opts ...Option) ([]ConsensusResult[T], error) {
+	docs, err := defra.QueryArray[map[string]any](ctx, defraNode, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return []ConsensusResult[T]{}, nil
+	}
+
+	docIds := make([]string, 0, len(docs))
+	keys := make([]string, 0, len(docs))
+	for i, doc := range docs {
+		docId, ok := doc[docIdField].(string)
+		if !ok || docId == "" {
+			return nil, fmt.Errorf("document at index %d has no %s; consensus queries must select %s", i, docIdField, docIdField)
+		}
+		keyValue, ok := doc[keyField]
+		if !ok || keyValue == nil {
+			return nil, fmt.Errorf("document %s has no %s; consensus queries must select the natural key field", docId, keyField)
+		}
+		docIds = append(docIds, docId)
+		keys = append(keys, naturalKey(keyValue))
+	}
+
+	signers, err := getAttestingIdentities(ctx, defraNode, viewName, docIds, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	candidatesByKey := map[string][]Candidate[T]{}
+	for i, doc := range docs {
+		candidate, err := newCandidate[T](docIds[i], doc, signers[docIds[i]])
+		if err != nil {
+			return nil, err
+		}
+		candidatesByKey[keys[i]] = append(candidatesByKey[keys[i]], candidate)
+	}
+
+	results := make([]ConsensusResult[T], 0, len(candidatesByKey))
+	for key, candidates := range candidatesByKey {
+		results = append(results, resolveConsensus(key, candidates))
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Key < results[j].Key })
+	return results, nil
+}
+
+// QuerySingleByConsensus behaves like QueryArrayByConsensus, but returns the result for the given natural key only
+func QuerySingleByConsensus[T any](ctx context.Context, defraNode *node.Node, viewName string, query string, keyField string, key string, opts ...Option) (ConsensusResult[T], error) {
+	results, err := QueryArrayByConsensus[T](ctx, defraNode, viewName, query, keyField, opts...)
+	if err != nil {
+		return ConsensusResult[T]{}, err
+	}
+	for _, result := range results {
+		if result.Key == key {
+			return result, nil
+		}
+	}
+	return ConsensusResult[T]{}, fmt.Errorf("no documents in %s with %s %s", viewName, keyField, key)
+}
+
+// naturalKey formats a key field's value the way it's written in the query. Numbers decode from JSON as float64,
+// which fmt would print in exponent form once they're large, e.g. 2.3e+07 for block 23000000.
+func naturalKey(value any) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func newCandidate[T any](docId string, doc map[string]any, signers map[string]struct{}) (Candidate[T], error) {
+	candidate := Candidate[T]{DocId: docId, Signers: make([]string, 0, len(signers))}
+	for identity := range signers {
+		candidate.Signers = append(candidate.Signers, identity)
+	}
+	sort.Strings(candidate.Signers)
+
+	// Round-trip through JSON so the caller receives their own struct type, mirroring the defra query helpers
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		return candidate, fmt.Errorf("failed to marshal document %s: %w", docId, err)
+	}
+	if err := json.Unmarshal(docBytes, &candidate.Doc); err != nil {
+		return candidate, fmt.Errorf("failed to unmarshal document %s: %w", docId, err)
+	}
+	return candidate, nil
+}
+
+// resolveConsensus ranks the candidates for a key by attestations, breaking ties by lowest doc ID so that every reader picks the same winner
+func resolveConsensus[T any](key string, candidates []Candidate[T]) ConsensusResult[T] {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Attestations() != candidates[j].Attestations() {
+			return candidates[i].Attestations() > candidates[j].Attestations()
+		}
+		return candidates[i].DocId < candidates[j].DocId
+	})
+
+	result := ConsensusResult[T]{
+		Key:    key,
+		Winner: candidates[0],
+		Losers: candidates[1:],
+	}
+	result.Contested = len(result.Losers) > 0 && result.Losers[0].Attestations() == result.Winner.Attestations()
+	return result
+}
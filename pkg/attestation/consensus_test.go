@@ -0,0 +1,68 @@
+package attestation
+
+import (
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveConsensus(t *testing.T) {
+	candidates := []Candidate[sampleViewDoc]{
+		{DocId: "malicious", Signers: []string{"attacker"}},
+		{DocId: "honest", Signers: []string{"indexera", "indexerb", "indexerc"}},
+		{DocId: "stale", Signers: []string{"indexerd"}},
+	}
+
+	result := resolveConsensus("hash", candidates)
+	require.Equal(t, "hash", result.Key)
+	require.Equal(t, "honest", result.Winner.DocId)
+	require.Equal(t, 3, result.Winner.Attestations())
+	require.Len(t, result.Losers, 2)
+	require.Equal(t, "malicious", result.Losers[0].DocId) // ties between losers are broken by doc ID
+	require.False(t, result.Contested)
+}
+
+func TestResolveConsensusTie(t *testing.T) {
+	candidates := []Candidate[sampleViewDoc]{
+		{DocId: "b", Signers: []string{"indexera"}},
+		{DocId: "a", Signers: []string{"indexerb"}},
+	}
+
+	result := resolveConsensus("hash", candidates)
+	require.Equal(t, "a", result.Winner.DocId)
+	require.True(t, result.Contested)
+
+	single := resolveConsensus("hash", []Candidate[sampleViewDoc]{{DocId: "only"}})
+	require.Equal(t, "only", single.Winner.DocId)
+	require.Empty(t, single.Losers)
+	require.False(t, single.Contested)
+}
+
+func TestNaturalKey(t *testing.T) {
+	require.Equal(t, "23000000", naturalKey(float64(23000000)))
+	require.Equal(t, "1.5", naturalKey(1.5))
+	require.Equal(t, "0xabc", naturalKey("0xabc"))
+	require.Equal(t, "true", naturalKey(true))
+}
+
+func TestQuerySingleByConsensusNumericKey(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleBlock { number: Int }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+	err = AddAttestationRecordCollection(ctx, defraNode, "SampleBlock")
+	require.NoError(t, err)
+
+	type sampleBlock struct {
+		DocId  string `json:"_docID"`
+		Number int    `json:"number"`
+	}
+	created, err := defra.PostMutation[sampleBlock](ctx, defraNode, `mutation { create_SampleBlock(input: { number: 23000000 }) { _docID } }`)
+	require.NoError(t, err)
+
+	result, err := QuerySingleByConsensus[sampleBlock](ctx, defraNode, "SampleBlock", "SampleBlock { _docID number }", "number", "23000000")
+	require.NoError(t, err)
+	require.Equal(t, created.DocId, result.Winner.DocId)
+	require.Equal(t, 23000000, result.Winner.Doc.Number)
+}
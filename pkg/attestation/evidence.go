@@ -0,0 +1,325 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// EvidenceFormat is the version of the Evidence serialisation produced by this package
+const EvidenceFormat = 1
+
+var ErrInvalidEvidence = errors.New("invalid misbehaviour evidence")
+
+// Evidence is a self-contained proof that an identity signed updates to a write-once View document, either overwriting
+// the indexed data or padding `_version` to inflate its attestations.
+// It carries the raw commit and signature blocks, so it can be checked with VerifyEvidence without access to a defra node.
+type Evidence struct {
+	Format     int    `json:"format"`
+	Collection string `json:"collection"`
+	DocId      string `json:"doc_id"`
+	// Offender is the identity that signed the updates
+	Offender string `json:"offender"`
+	// Blocks holds the offender's update blocks, along with the blocks they were written on top of
+	Blocks []EvidenceBlock `json:"blocks"`
+}
+
+// EvidenceBlock is a DefraDB commit block, its signature block and the claims they prove
+type EvidenceBlock struct {
+	CID           string `json:"cid"`
+	Height        uint   `json:"height"`
+	Identity      string `json:"identity"`
+	SignatureType string `json:"signature_type"`
+	// Data holds the commit block's dag-cbor bytes
+	Data         []byte `json:"data"`
+	SignatureCID string `json:"signature_cid"`
+	// Signature holds the signature block's dag-cbor bytes
+	Signature []byte `json:"signature"`
+}
+
+// JSON returns the evidence in its serialised form, e.g. for submission to ShinzoHub
+func (e *Evidence) JSON() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// ParseEvidence deserialises evidence produced by Evidence.JSON. It does not verify it - see VerifyEvidence.
+func ParseEvidence(data []byte) (*Evidence, error) {
+	evidence := &Evidence{}
+	if err := json.Unmarshal(data, evidence); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal evidence: %w", err)
+	}
+	return evidence, nil
+}
+
+// CollectEvidence builds an Evidence bundle for every identity that has signed an update (a `_version` with height > 1)
+// to the given View document. Returns an empty slice if the document has only been created.
+func CollectEvidence(ctx context.Context, defraNode *node.Node, collectionName string, docId string) ([]Evidence, error) {
+	docs, err := getDocVersions(ctx, defraNode, collectionName, []string{docId})
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no document %s in %s", docId, collectionName)
+	}
+
+	updatesByOffender := map[string][]Version{}
+	for _, version := range docs[0].Version {
+		// Unsigned updates can't be attributed to anyone
+		if version.Height > 1 && version.Signature.Identity != "" {
+			updatesByOffender[version.Signature.Identity] = append(updatesByOffender[version.Signature.Identity], version)
+		}
+	}
+	offenders := make([]string, 0, len(updatesByOffender))
+	for offender := range updatesByOffender {
+		offenders = append(offenders, offender)
+	}
+	sort.Strings(offenders)
+
+	bundles := make([]Evidence, 0, len(offenders))
+	for _, offender := range offenders {
+		evidence := Evidence{
+			Format:     EvidenceFormat,
+			Collection: collectionName,
+			DocId:      docId,
+			Offender:   offender,
+		}
+
+		included := map[string]bool{}
+		pending := []cid.Cid{}
+		for _, version := range updatesByOffender[offender] {
+			updateCid, err := cid.Decode(version.CID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version CID %s: %w", version.CID, err)
+			}
+			block, heads, err := loadEvidenceBlock(ctx, defraNode, updateCid)
+			if err != nil {
+				return nil, err
+			}
+			included[block.CID] = true
+			evidence.Blocks = append(evidence.Blocks, block)
+			pending = append(pending, heads...)
+		}
+
+		// Include the blocks the updates were written on top of, so the conflict can be seen in the bundle
+		for _, headCid := range pending {
+			if included[headCid.String()] {
+				continue
+			}
+			block, _, err := loadEvidenceBlock(ctx, defraNode, headCid)
+			if err != nil {
+				return nil, err
+			}
+			included[block.CID] = true
+			evidence.Blocks = append(evidence.Blocks, block)
+		}
+
+		sort.Slice(evidence.Blocks, func(i, j int) bool {
+			if evidence.Blocks[i].Height != evidence.Blocks[j].Height {
+				return evidence.Blocks[i].Height < evidence.Blocks[j].Height
+			}
+			return evidence.Blocks[i].CID < evidence.Blocks[j].CID
+		})
+		bundles = append(bundles, evidence)
+	}
+	return bundles, nil
+}
+
+// VerifyEvidence checks an Evidence bundle using only its contents: every block must hash to its CID, carry a valid
+// signature by the identity it claims and belong to the accused document; the offender must have signed at least one
+// update; and every other block must be one that an update was written on top of.
+func VerifyEvidence(evidence Evidence) error {
+	if evidence.Format != EvidenceFormat {
+		return fmt.Errorf("%w: unsupported format %d", ErrInvalidEvidence, evidence.Format)
+	}
+	if evidence.Offender == "" || evidence.DocId == "" {
+		return fmt.Errorf("%w: missing offender or doc ID", ErrInvalidEvidence)
+	}
+
+	referenced := map[string]bool{}
+	offences := map[string]bool{}
+	for _, block := range evidence.Blocks {
+		commit, err := verifyEvidenceBlock(block)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidEvidence, err)
+		}
+		if commit.docId != evidence.DocId {
+			return fmt.Errorf("%w: block %s belongs to document %s, not %s", ErrInvalidEvidence, block.CID, commit.docId, evidence.DocId)
+		}
+		if commit.height > 1 && normalizeIdentity(block.Identity) == normalizeIdentity(evidence.Offender) {
+			offences[block.CID] = true
+		}
+		for _, head := range commit.heads {
+			referenced[head.String()] = true
+		}
+	}
+
+	if len(offences) == 0 {
+		return fmt.Errorf("%w: no update signed by %s", ErrInvalidEvidence, evidence.Offender)
+	}
+	for _, block := range evidence.Blocks {
+		if !offences[block.CID] && !referenced[block.CID] {
+			return fmt.Errorf("%w: block %s is unrelated to the offending updates", ErrInvalidEvidence, block.CID)
+		}
+	}
+	return nil
+}
+
+// loadEvidenceBlock reads a commit block and its signature block from the node's blockstore, returning them along with the commit's heads
+func loadEvidenceBlock(ctx context.Context, defraNode *node.Node, blockCid cid.Cid) (EvidenceBlock, []cid.Cid, error) {
+	data, err := loadRawBlock(ctx, defraNode, blockCid)
+	if err != nil {
+		return EvidenceBlock{}, nil, err
+	}
+	block, err := decodeVerifiedBlock(blockCid, data)
+	if err != nil {
+		return EvidenceBlock{}, nil, err
+	}
+	commit, err := readCommitBlock(block)
+	if err != nil {
+		return EvidenceBlock{}, nil, fmt.Errorf("%s is not a commit block: %w", blockCid, err)
+	}
+
+	evidenceBlock := EvidenceBlock{CID: blockCid.String(), Height: commit.height, Data: data}
+	signatureCid, err := getSignatureLink(block)
+	if err != nil {
+		// Blocks the offender overwrote may be unsigned; they are still part of the conflict
+		if errors.Is(err, ErrVersionUnsigned) {
+			return evidenceBlock, commit.heads, nil
+		}
+		return EvidenceBlock{}, nil, fmt.Errorf("%s: %w", blockCid, err)
+	}
+	signature, err := loadRawBlock(ctx, defraNode, signatureCid)
+	if err != nil {
+		return EvidenceBlock{}, nil, err
+	}
+	signatureBlock, err := decodeVerifiedBlock(signatureCid, signature)
+	if err != nil {
+		return EvidenceBlock{}, nil, err
+	}
+	signatureType, identity, _, err := readSignatureBlock(signatureBlock)
+	if err != nil {
+		return EvidenceBlock{}, nil, fmt.Errorf("%s has a malformed signature block: %w", blockCid, err)
+	}
+
+	evidenceBlock.SignatureCID = signatureCid.String()
+	evidenceBlock.Signature = signature
+	evidenceBlock.SignatureType = signatureType
+	evidenceBlock.Identity = identity
+	return evidenceBlock, commit.heads, nil
+}
+
+// verifyEvidenceBlock checks a block's bytes against its CID and claims, and verifies its signature if it has one
+func verifyEvidenceBlock(evidenceBlock EvidenceBlock) (commitBlock, error) {
+	blockCid, err := cid.Decode(evidenceBlock.CID)
+	if err != nil {
+		return commitBlock{}, fmt.Errorf("invalid block CID %s: %w", evidenceBlock.CID, err)
+	}
+	block, err := decodeVerifiedBlock(blockCid, evidenceBlock.Data)
+	if err != nil {
+		return commitBlock{}, err
+	}
+	commit, err := readCommitBlock(block)
+	if err != nil {
+		return commitBlock{}, fmt.Errorf("%s is not a commit block: %w", evidenceBlock.CID, err)
+	}
+	if commit.height != evidenceBlock.Height {
+		return commitBlock{}, fmt.Errorf("%s claims height %d but block has height %d", evidenceBlock.CID, evidenceBlock.Height, commit.height)
+	}
+
+	signatureCid, err := getSignatureLink(block)
+	if errors.Is(err, ErrVersionUnsigned) && evidenceBlock.Identity == "" {
+		return commit, nil
+	}
+	if err != nil {
+		return commitBlock{}, fmt.Errorf("%s: %w", evidenceBlock.CID, err)
+	}
+	if signatureCid.String() != evidenceBlock.SignatureCID {
+		return commitBlock{}, fmt.Errorf("%s links signature %s, not %s", evidenceBlock.CID, signatureCid, evidenceBlock.SignatureCID)
+	}
+	signatureBlock, err := decodeVerifiedBlock(signatureCid, evidenceBlock.Signature)
+	if err != nil {
+		return commitBlock{}, err
+	}
+	signatureType, identity, value, err := readSignatureBlock(signatureBlock)
+	if err != nil {
+		return commitBlock{}, fmt.Errorf("%s has a malformed signature block: %w", evidenceBlock.CID, err)
+	}
+	if identity != evidenceBlock.Identity || signatureType != evidenceBlock.SignatureType {
+		return commitBlock{}, fmt.Errorf("%s claims %s signature by %s but block is signed with %s by %s: %w",
+			evidenceBlock.CID, evidenceBlock.SignatureType, evidenceBlock.Identity, signatureType, identity, ErrSignatureMismatch)
+	}
+	if err := verifyBlockSignature(evidenceBlock.CID, block, signatureType, identity, value); err != nil {
+		return commitBlock{}, err
+	}
+	return commit, nil
+}
+
+type commitBlock struct {
+	docId  string
+	height uint
+	heads  []cid.Cid
+}
+
+// readCommitBlock extracts the doc ID, height and heads of a DefraDB commit block: { delta: { <crdt>: { docID, priority, ... } }, heads, ... }
+func readCommitBlock(block datamodel.Node) (commitBlock, error) {
+	deltaUnion, err := block.LookupByString("delta")
+	if err != nil {
+		return commitBlock{}, err
+	}
+	if deltaUnion.Length() != 1 {
+		return commitBlock{}, fmt.Errorf("delta has %d members, expected 1", deltaUnion.Length())
+	}
+	_, delta, err := deltaUnion.MapIterator().Next()
+	if err != nil {
+		return commitBlock{}, err
+	}
+
+	docIdNode, err := delta.LookupByString("docID")
+	if err != nil {
+		return commitBlock{}, err
+	}
+	docId, err := docIdNode.AsBytes()
+	if err != nil {
+		return commitBlock{}, err
+	}
+	priorityNode, err := delta.LookupByString("priority")
+	if err != nil {
+		return commitBlock{}, err
+	}
+	priority, err := priorityNode.AsInt()
+	if err != nil {
+		return commitBlock{}, err
+	}
+	if priority < 0 {
+		return commitBlock{}, fmt.Errorf("negative priority %d", priority)
+	}
+
+	commit := commitBlock{docId: string(docId), height: uint(priority)}
+	headsNode, err := block.LookupByString("heads")
+	if err != nil || headsNode.IsNull() {
+		return commit, nil // Creates have no heads
+	}
+	for iterator := headsNode.ListIterator(); iterator != nil && !iterator.Done(); {
+		_, headNode, err := iterator.Next()
+		if err != nil {
+			return commitBlock{}, err
+		}
+		link, err := headNode.AsLink()
+		if err != nil {
+			return commitBlock{}, err
+		}
+		head, ok := link.(cidlink.Link)
+		if !ok {
+			return commitBlock{}, fmt.Errorf("unsupported head link type %T", link)
+		}
+		commit.heads = append(commit.heads, head.Cid)
+	}
+	return commit, nil
+}
@@ -0,0 +1,63 @@
+package attestation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectAndVerifyEvidence(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+
+	untouched, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Untouched" }) { _docID } }`)
+	require.NoError(t, err)
+	evidence, err := CollectEvidence(ctx, defraNode, "SampleView", untouched.DocId)
+	require.NoError(t, err)
+	require.Empty(t, evidence)
+
+	// Overwrite a doc, then pad its _version with a second update
+	created, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Original" }) { _docID } }`)
+	require.NoError(t, err)
+	for _, name := range []string{"Overwritten", "Padded"} {
+		_, err = defra.PostMutation[sampleViewDoc](ctx, defraNode, fmt.Sprintf(`mutation { update_SampleView(docID: "%s", input: { name: "%s" }) { _docID } }`, created.DocId, name))
+		require.NoError(t, err)
+	}
+
+	evidence, err = CollectEvidence(ctx, defraNode, "SampleView", created.DocId)
+	require.NoError(t, err)
+	require.Len(t, evidence, 1)
+	bundle := evidence[0]
+	require.Equal(t, created.DocId, bundle.DocId)
+	require.NotEmpty(t, bundle.Offender)
+	require.Len(t, bundle.Blocks, 3) // Two updates and the create they were written on top of
+	require.Equal(t, uint(1), bundle.Blocks[0].Height)
+	require.NoError(t, VerifyEvidence(bundle))
+
+	// The serialised bundle verifies on its own
+	data, err := bundle.JSON()
+	require.NoError(t, err)
+	parsed, err := ParseEvidence(data)
+	require.NoError(t, err)
+	require.NoError(t, VerifyEvidence(*parsed))
+
+	// Accusing someone else fails
+	accused := *parsed
+	accused.Offender = "02" + bundle.Offender[2:] + "00"
+	require.ErrorIs(t, VerifyEvidence(accused), ErrInvalidEvidence)
+
+	// As does tampering with a block
+	tampered, err := ParseEvidence(data)
+	require.NoError(t, err)
+	tampered.Blocks[2].Data[len(tampered.Blocks[2].Data)-1] ^= 0xff
+	require.ErrorIs(t, VerifyEvidence(*tampered), ErrInvalidEvidence)
+
+	// And evidence made up only of the honest create
+	createOnly := *parsed
+	createOnly.Blocks = parsed.Blocks[:1]
+	require.ErrorIs(t, VerifyEvidence(createOnly), ErrInvalidEvidence)
+}
@@ -0,0 +1,264 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+const docIdField = "_docID"
+
+// QueryArrayWithMinimumAttestations executes a GraphQL query against a View and returns only the documents
+// attested by at least minimumAttestations distinct identities.
+// The query must select `_docID` on the View's documents so that they can be matched against their attestations.
+// Options such as WithBatchSize and WithConcurrency control how the attestations are fetched, and WithTrustedSigners
+// limits which identities count.
+func QueryArrayWithMinimumAttestations[T any](ctx context.Context, defraNode *node.Node, viewName string, query string, minimumAttestations int, opts ...Option) ([]T, error) {
+	return QueryArrayWithTrustedAttestations[T](ctx, defraNode, viewName, query, minimumAttestations, nil, opts...)
+}
+
+// QuerySingleWithMinimumAttestations executes a GraphQL query against a View and returns the first document
+// attested by at least minimumAttestations distinct identities.
+// The query must select `_docID` on the View's documents so that they can be matched against their attestations.
+func QuerySingleWithMinimumAttestations[T any](ctx context.Context, defraNode *node.Node, viewName string, query string, minimumAttestations int, opts ...Option) (T, error) {
+	return QuerySingleWithTrustedAttestations[T](ctx, defraNode, viewName, query, minimumAttestations, nil, opts...)
+}
+
+// QueryArrayWithTrustedAttestations behaves like QueryArrayWithMinimumAttestations, but only signatures by identities
+// in the trusted allowlist count towards the threshold. A nil allowlist leaves the choice to opts (see
+// WithTrustedSigners), trusting every identity if they don't make one.
+func QueryArrayWithTrustedAttestations[T any](ctx context.Context, defraNode *node.Node, viewName string, query string, minimumAttestations int, trusted *TrustedSigners, opts ...Option) ([]T, error) {
+	docs, err := defra.QueryArray[map[string]any](ctx, defraNode, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if trusted != nil {
+		opts = append(append([]Option{}, opts...), WithTrustedSigners(trusted))
+	}
+	attestedDocs, err := filterByMinimumAttestations(ctx, defraNode, viewName, docs, minimumAttestations, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round-trip through JSON so the caller receives their own struct type, mirroring the defra query helpers
+	docBytes, err := json.Marshal(attestedDocs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal attested documents: %w", err)
+	}
+	result := []T{}
+	if err := json.Unmarshal(docBytes, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal attested documents: %w", err)
+	}
+	return result, nil
+}
+
+// QuerySingleWithTrustedAttestations behaves like QuerySingleWithMinimumAttestations, but only signatures by identities
+// in the trusted allowlist count towards the threshold. A nil allowlist leaves the choice to opts.
+func QuerySingleWithTrustedAttestations[T any](ctx context.Context, defraNode *node.Node, viewName string, query string, minimumAttestations int, trusted *TrustedSigners, opts ...Option) (T, error) {
+	var result T
+	results, err := QueryArrayWithTrustedAttestations[T](ctx, defraNode, viewName, query, minimumAttestations, trusted, opts...)
+	if err != nil {
+		return result, err
+	}
+	if len(results) == 0 {
+		return result, fmt.Errorf("no documents in %s meet the minimum attestation threshold of %d", viewName, minimumAttestations)
+	}
+	return results[0], nil
+}
+
+// filterByMinimumAttestations drops any document attested by fewer than minimumAttestations distinct trusted identities
+func filterByMinimumAttestations(ctx context.Context, defraNode *node.Node, viewName string, docs []map[string]any, minimumAttestations int, opts ...Option) ([]map[string]any, error) {
+	if minimumAttestations <= 0 || len(docs) == 0 {
+		return docs, nil
+	}
+
+	docIds := make([]string, 0, len(docs))
+	for i, doc := range docs {
+		docId, ok := doc[docIdField].(string)
+		if !ok || docId == "" {
+			return nil, fmt.Errorf("document at index %d has no %s; queries filtered by attestations must select %s", i, docIdField, docIdField)
+		}
+		docIds = append(docIds, docId)
+	}
+
+	signers, err := getAttestingIdentities(ctx, defraNode, viewName, docIds, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	attestedDocs := make([]map[string]any, 0, len(docs))
+	for i, doc := range docs {
+		if len(signers[docIds[i]]) >= minimumAttestations {
+			attestedDocs = append(attestedDocs, doc)
+		}
+	}
+	return attestedDocs, nil
+}
+
+// getAttestingIdentities returns the set of distinct trusted identities (see WithTrustedSigners) that have attested to each
+// of the given View docs. An identity attests to a doc if it signed one of the doc's `_version` entries,
+// or if it signed one of the source CIDs listed in the doc's AttestationRecord.
+func getAttestingIdentities(ctx context.Context, defraNode *node.Node, viewName string, docIds []string, opts ...Option) (map[string]map[string]struct{}, error) {
+	trusted := newOptions(opts...).trusted
+	signers := make(map[string]map[string]struct{}, len(docIds))
+	for _, docId := range docIds {
+		signers[docId] = map[string]struct{}{}
+	}
+
+	versionedDocs, err := getDocVersions(ctx, defraNode, viewName, docIds, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range versionedDocs {
+		for identity := range trustedIdentities(doc.Version, trusted) {
+			signers[doc.DocId][identity] = struct{}{}
+		}
+	}
+
+	records, err := queryAttestationRecords(ctx, defraNode, viewName, docIds, opts...)
+	if err != nil {
+		return nil, err
+	}
+	recordCids := []string{}
+	for _, record := range records {
+		recordCids = append(recordCids, record.CIDs...)
+	}
+	signedCommits, err := getSignedCommits(ctx, defraNode, recordCids, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		docSigners, ok := signers[record.AttestedDocId]
+		if !ok {
+			continue
+		}
+		sourceDocId := record.sourceDocId()
+		for _, recordCid := range record.CIDs {
+			// A record only attests to commits of its own source doc, however many other commits it lists
+			c, ok := signedCommits[recordCid]
+			if ok && c.DocId == sourceDocId && trusted.IsTrusted(c.Signature.Identity) {
+				docSigners[normalizeIdentity(c.Signature.Identity)] = struct{}{}
+			}
+		}
+	}
+
+	return signers, nil
+}
+
+type versionedDoc struct {
+	DocId   string    `json:"_docID"`
+	Version []Version `json:"_version"`
+}
+
+// getDocVersions fetches the `_version` entries for the given docs in a collection, in batches (see WithBatchSize)
+func getDocVersions(ctx context.Context, defraNode *node.Node, collectionName string, docIds []string, opts ...Option) ([]versionedDoc, error) {
+	query := fmt.Sprintf(`query($docIds: [ID!]) {
+		%s (filter: {_docID: {_in: $docIds}}) {
+			_docID
+			_version {
+				cid
+				height
+				signature {
+					type
+					identity
+					value
+				}
+			}
+		}
+	}`, collectionName)
+	return queryInBatches(ctx, docIds, newOptions(opts...), func(ctx context.Context, batch []any) ([]versionedDoc, error) {
+		docs, err := defra.QueryArrayWithVariables[versionedDoc](ctx, defraNode, query, map[string]any{"docIds": batch})
+		if err != nil {
+			return nil, fmt.Errorf("Error fetching versions for %s docs: %w", collectionName, err)
+		}
+		return docs, nil
+	})
+}
+
+type commit struct {
+	CID       string    `json:"cid"`
+	DocId     string    `json:"docID"`
+	Height    uint      `json:"height"`
+	Signature Signature `json:"signature"`
+}
+
+// getSignedCommits returns the doc and signer of each of the commits with the given CIDs, keyed by CID, in batches (see
+// WithBatchSize). Commits that are unknown or unsigned are left out, as are malformed CIDs, which can't reference a
+// commit and so can't carry an attestation either.
+func getSignedCommits(ctx context.Context, defraNode *node.Node, commitCids []string, opts ...Option) (map[string]commit, error) {
+	validCids := make([]string, 0, len(commitCids))
+	for _, commitCid := range commitCids {
+		if isValidCid(commitCid) {
+			validCids = append(validCids, commitCid)
+		}
+	}
+
+	signed, err := queryInBatches(ctx, validCids, newOptions(opts...), func(ctx context.Context, batch []any) ([]commit, error) {
+		commits, err := queryCommitSignatures(ctx, defraNode, batch)
+		if defra.IsCommitNotFound(err) {
+			// The whole query fails if any commit is unknown, so look the batch's commits up one at a time instead
+			commits = []commit{}
+			for _, commitCid := range batch {
+				found, err := queryCommitSignatures(ctx, defraNode, []any{commitCid})
+				if err != nil && !defra.IsCommitNotFound(err) {
+					return nil, err
+				}
+				commits = append(commits, found...)
+			}
+			return commits, nil
+		}
+		return commits, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make(map[string]commit, len(signed))
+	for _, c := range signed {
+		if c.Signature.Identity != "" {
+			commits[c.CID] = c
+		}
+	}
+	return commits, nil
+}
+
+// queryCommitSignatures fetches the docs and signatures of the commits with the given CIDs in one query. `_commits` only takes a
+// single cid, so each CID gets its own aliased selection and variable.
+func queryCommitSignatures(ctx context.Context, defraNode *node.Node, commitCids []any) ([]commit, error) {
+	variables := make(map[string]any, len(commitCids))
+	declarations := make([]string, 0, len(commitCids))
+	var selections strings.Builder
+	for i, commitCid := range commitCids {
+		name := fmt.Sprintf("c%d", i)
+		variables[name] = commitCid
+		declarations = append(declarations, fmt.Sprintf("$%s: ID", name))
+		fmt.Fprintf(&selections, "\t\t%s: _commits(cid: $%s) { cid docID signature { identity } }\n", name, name)
+	}
+	query := fmt.Sprintf("query(%s) {\n%s\t}", strings.Join(declarations, ", "), selections.String())
+
+	results, err := defra.QueryWithVariables[map[string][]commit](ctx, defraNode, query, variables)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching commits: %w", err)
+	}
+	commits := make([]commit, 0, len(commitCids))
+	for i, commitCid := range commitCids {
+		found := results[fmt.Sprintf("c%d", i)]
+		if len(found) == 0 {
+			continue
+		}
+		// Report the commit under the CID it was asked for, which is how records refer to it
+		commits = append(commits, commit{CID: commitCid.(string), DocId: found[0].DocId, Signature: found[0].Signature})
+	}
+	return commits, nil
+}
+
+func isValidCid(commitCid string) bool {
+	_, err := cid.Decode(commitCid)
+	return err == nil
+}
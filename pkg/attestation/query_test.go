@@ -0,0 +1,208 @@
+package attestation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/stretchr/testify/require"
+)
+
+type sampleViewDoc struct {
+	DocId string `json:"_docID"`
+	Name  string `json:"name"`
+}
+
+func TestQueryArrayWithMinimumAttestations(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+	err = AddAttestationRecordCollection(ctx, defraNode, "SampleView")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		mutation := fmt.Sprintf(`mutation { create_SampleView(input: { name: "Doc %d" }) { name } }`, i)
+		_, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, mutation)
+		require.NoError(t, err)
+	}
+
+	query := "SampleView { _docID name }"
+
+	// Every doc has been signed by our own node, so a threshold of 1 keeps them all
+	docs, err := QueryArrayWithMinimumAttestations[sampleViewDoc](ctx, defraNode, "SampleView", query, 1)
+	require.NoError(t, err)
+	require.Len(t, docs, 3)
+
+	// No other identities have attested to these docs
+	docs, err = QueryArrayWithMinimumAttestations[sampleViewDoc](ctx, defraNode, "SampleView", query, 2)
+	require.NoError(t, err)
+	require.Len(t, docs, 0)
+
+	_, err = QuerySingleWithMinimumAttestations[sampleViewDoc](ctx, defraNode, "SampleView", query, 2)
+	require.Error(t, err)
+
+	doc, err := QuerySingleWithMinimumAttestations[sampleViewDoc](ctx, defraNode, "SampleView", query, 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, doc.DocId)
+
+	// Our node's signatures don't count if it isn't on the allowlist
+	docs, err = QueryArrayWithTrustedAttestations[sampleViewDoc](ctx, defraNode, "SampleView", query, 1, NewTrustedSigners("someOtherIndexer"))
+	require.NoError(t, err)
+	require.Len(t, docs, 0)
+}
+
+func TestQueryArrayWithMinimumAttestationsRequiresDocId(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+
+	_, err = defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Doc" }) { name } }`)
+	require.NoError(t, err)
+
+	_, err = QueryArrayWithMinimumAttestations[sampleViewDoc](ctx, defraNode, "SampleView", "SampleView { name }", 1)
+	require.Error(t, err)
+}
+
+func TestGetDocVersionsInBatches(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+
+	docIds := []string{}
+	for i := 0; i < 3; i++ {
+		mutation := fmt.Sprintf(`mutation { create_SampleView(input: { name: "Doc %d" }) { _docID } }`, i)
+		created, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, mutation)
+		require.NoError(t, err)
+		docIds = append(docIds, created.DocId)
+	}
+
+	// Duplicates are fetched once, and IDs are passed as variables rather than spliced into the query
+	requested := append([]string{docIds[0], `bae-"injected"`}, docIds...)
+	docs, err := getDocVersions(ctx, defraNode, "SampleView", requested, WithBatchSize(2), WithConcurrency(2))
+	require.NoError(t, err)
+	require.Len(t, docs, 3)
+	found := []string{}
+	for _, doc := range docs {
+		require.NotEmpty(t, doc.Version)
+		found = append(found, doc.DocId)
+	}
+	require.ElementsMatch(t, docIds, found)
+
+	docs, err = getDocVersions(ctx, defraNode, "SampleView", nil)
+	require.NoError(t, err)
+	require.Empty(t, docs)
+}
+
+func TestGetSignedCommitsInBatches(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+
+	docIds := []string{}
+	for i := 0; i < 3; i++ {
+		mutation := fmt.Sprintf(`mutation { create_SampleView(input: { name: "Doc %d" }) { _docID } }`, i)
+		created, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, mutation)
+		require.NoError(t, err)
+		docIds = append(docIds, created.DocId)
+	}
+	docs, err := getDocVersions(ctx, defraNode, "SampleView", docIds)
+	require.NoError(t, err)
+	expected := map[string]commit{}
+	cids := []string{}
+	for _, doc := range docs {
+		version := doc.Version[0]
+		expected[version.CID] = commit{CID: version.CID, DocId: doc.DocId, Signature: Signature{Identity: version.Signature.Identity}}
+		cids = append(cids, version.CID)
+	}
+
+	// Unknown and malformed CIDs, even in a batch with known ones, are left out rather than failing the lookup
+	unknownCid := "bafyreigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"
+	requested := append([]string{unknownCid, "notACid", cids[0]}, cids...)
+	commits, err := getSignedCommits(ctx, defraNode, requested, WithBatchSize(2))
+	require.NoError(t, err)
+	require.Equal(t, expected, commits)
+}
+
+func TestAttestingIdentitiesOnlyCountSourceDocCommits(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String } type Other { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+	err = AddAttestationRecordCollection(ctx, defraNode, "SampleView")
+	require.NoError(t, err)
+
+	source, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Source" }) { _docID } }`)
+	require.NoError(t, err)
+	unrelated, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Unrelated" }) { _docID } }`)
+	require.NoError(t, err)
+	docs, err := getDocVersions(ctx, defraNode, "SampleView", []string{source.DocId})
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	sourceCid := docs[0].Version[0].CID
+
+	// The attested docs aren't in the view, so only their records can credit a signer
+	honest, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_Other(input: { name: "Honest" }) { _docID } }`)
+	require.NoError(t, err)
+	forged, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_Other(input: { name: "Forged" }) { _docID } }`)
+	require.NoError(t, err)
+	for attested, sourceDoc := range map[string]string{honest.DocId: source.DocId, forged.DocId: unrelated.DocId} {
+		mutation := fmt.Sprintf(`mutation { create_AttestationRecord_SampleView(input: { attested_doc: %q, source_doc: %q, CIDs: [%q] }) { _docID } }`, attested, sourceDoc, sourceCid)
+		_, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, mutation)
+		require.NoError(t, err)
+	}
+
+	signers, err := getAttestingIdentities(ctx, defraNode, "SampleView", []string{honest.DocId, forged.DocId})
+	require.NoError(t, err)
+	require.Len(t, signers[honest.DocId], 1)
+	require.Empty(t, signers[forged.DocId], "a commit of another doc mustn't count towards the record's attestations")
+}
+
+func TestQueryArrayWithMinimumAttestationsOptions(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+	err = AddAttestationRecordCollection(ctx, defraNode, "SampleView")
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		mutation := fmt.Sprintf(`mutation { create_SampleView(input: { name: "Doc %d" }) { name } }`, i)
+		_, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, mutation)
+		require.NoError(t, err)
+	}
+
+	// Small batches give the same answer as the defaults
+	docs, err := QueryArrayWithMinimumAttestations[sampleViewDoc](ctx, defraNode, "SampleView", "SampleView { _docID name }", 1, WithBatchSize(2), WithConcurrency(1))
+	require.NoError(t, err)
+	require.Len(t, docs, 5)
+}
+
+func TestMinimumAttestationsHonoursTrustedSignersOption(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+	err = AddAttestationRecordCollection(ctx, defraNode, "SampleView")
+	require.NoError(t, err)
+
+	_, err = defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Doc" }) { name } }`)
+	require.NoError(t, err)
+	query := "SampleView { _docID name }"
+
+	// Our node's signature doesn't count when the allowlist passed as an option leaves it out
+	docs, err := QueryArrayWithMinimumAttestations[sampleViewDoc](ctx, defraNode, "SampleView", query, 1, WithTrustedSigners(NewTrustedSigners("someOtherIndexer")))
+	require.NoError(t, err)
+	require.Len(t, docs, 0)
+
+	_, err = QuerySingleWithMinimumAttestations[sampleViewDoc](ctx, defraNode, "SampleView", query, 1, WithTrustedSigners(NewTrustedSigners("someOtherIndexer")))
+	require.Error(t, err)
+
+	// An explicit allowlist takes precedence over the option
+	docs, err = QueryArrayWithTrustedAttestations[sampleViewDoc](ctx, defraNode, "SampleView", query, 1, NewTrustedSigners("someOtherIndexer"), WithTrustedSigners(nil))
+	require.NoError(t, err)
+	require.Len(t, docs, 0)
+}
@@ -0,0 +1,186 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// SourceDoc identifies a primitive doc that a View doc was derived from
+type SourceDoc struct {
+	Collection string
+	DocId      string
+}
+
+type storedAttestationRecord struct {
+	DocId string `json:"_docID"`
+	AttestationRecord
+}
+
+// WriteAttestationRecord records that a View doc was derived from the given source docs.
+// It collects the `_version` CIDs of each source doc and creates, or appends the new CIDs to, the matching `AttestationRecord_<view>` doc -
+// one record per (attested_doc, source_doc) pair.
+// Primitive collections use the condensed attestation record schema, which has no source_doc; their record collects the CIDs of all sources.
+// Records are read and then written, so callers should not write the same record from multiple goroutines at once.
+func WriteAttestationRecord(ctx context.Context, defraNode *node.Node, viewName string, viewDocId string, sources ...SourceDoc) ([]AttestationRecord, error) {
+	if viewDocId == "" {
+		return nil, fmt.Errorf("a view doc ID is required to write an attestation record")
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("at least one source doc is required to write an attestation record for %s", viewDocId)
+	}
+
+	cidsBySource := make(map[string][]string, len(sources))
+	sourceOrder := make([]string, 0, len(sources))
+	for _, source := range sources {
+		docs, err := getDocVersions(ctx, defraNode, source.Collection, []string{source.DocId})
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) == 0 {
+			return nil, fmt.Errorf("source doc %s not found in %s", source.DocId, source.Collection)
+		}
+		if _, ok := cidsBySource[source.DocId]; !ok {
+			sourceOrder = append(sourceOrder, source.DocId)
+		}
+		for _, version := range docs[0].Version {
+			cidsBySource[source.DocId] = append(cidsBySource[source.DocId], version.CID)
+		}
+	}
+
+	if isPrimitive(viewName) {
+		cids := []string{}
+		for _, sourceDocId := range sourceOrder {
+			cids = append(cids, cidsBySource[sourceDocId]...)
+		}
+		record, err := upsertAttestationRecord(ctx, defraNode, viewName, AttestationRecord{AttestedDocId: viewDocId, CIDs: cids})
+		if err != nil {
+			return nil, err
+		}
+		return []AttestationRecord{record}, nil
+	}
+
+	records := make([]AttestationRecord, 0, len(sourceOrder))
+	for _, sourceDocId := range sourceOrder {
+		record, err := upsertAttestationRecord(ctx, defraNode, viewName, AttestationRecord{
+			AttestedDocId: viewDocId,
+			SourceDocId:   sourceDocId,
+			CIDs:          cidsBySource[sourceDocId],
+		})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// upsertAttestationRecord creates the given record, or appends its CIDs to an existing record for the same attested (and source) doc
+func upsertAttestationRecord(ctx context.Context, defraNode *node.Node, viewName string, record AttestationRecord) (AttestationRecord, error) {
+	primitive := isPrimitive(viewName)
+	collectionName := fmt.Sprintf("AttestationRecord_%s", viewName)
+
+	var query string
+	variables := map[string]any{"attested": record.AttestedDocId}
+	if primitive {
+		query = fmt.Sprintf(`query($attested: String) {
+			%s (filter: {attested_doc: {_eq: $attested}}) {
+				_docID
+				attested_doc
+				CIDs
+			}
+		}`, collectionName)
+	} else {
+		query = fmt.Sprintf(`query($attested: String, $source: String) {
+			%s (filter: {attested_doc: {_eq: $attested}, source_doc: {_eq: $source}}) {
+				_docID
+				attested_doc
+				source_doc
+				CIDs
+			}
+		}`, collectionName)
+		variables["source"] = record.SourceDocId
+	}
+	existing, err := defra.QueryArrayWithVariables[storedAttestationRecord](ctx, defraNode, query, variables)
+	if err != nil {
+		return record, fmt.Errorf("Error fetching existing attestation record for %s: %w", record.AttestedDocId, err)
+	}
+
+	if len(existing) == 0 {
+		return createAttestationRecord(ctx, defraNode, collectionName, record, primitive)
+	}
+
+	stored := existing[0]
+	merged := mergeCIDs(stored.CIDs, record.CIDs)
+	if len(merged) == len(stored.CIDs) {
+		return stored.AttestationRecord, nil
+	}
+
+	cids, err := json.Marshal(merged)
+	if err != nil {
+		return record, fmt.Errorf("failed to encode CIDs: %w", err)
+	}
+	mutation := fmt.Sprintf(`mutation {
+		update_%s(docID: "%s", input: { CIDs: %s }) {
+			_docID
+		}
+	}`, collectionName, stored.DocId, cids)
+	if _, err := defra.PostMutation[storedAttestationRecord](ctx, defraNode, mutation); err != nil {
+		return record, fmt.Errorf("Error appending to attestation record %s: %w", stored.DocId, err)
+	}
+
+	stored.CIDs = merged
+	return stored.AttestationRecord, nil
+}
+
+func createAttestationRecord(ctx context.Context, defraNode *node.Node, collectionName string, record AttestationRecord, primitive bool) (AttestationRecord, error) {
+	// JSON string and list literals are valid GraphQL literals, so encoding the values this way escapes them safely
+	attested, err := json.Marshal(record.AttestedDocId)
+	if err != nil {
+		return record, fmt.Errorf("failed to encode attested doc ID: %w", err)
+	}
+	cids, err := json.Marshal(mergeCIDs(nil, record.CIDs))
+	if err != nil {
+		return record, fmt.Errorf("failed to encode CIDs: %w", err)
+	}
+
+	input := fmt.Sprintf("attested_doc: %s, CIDs: %s", attested, cids)
+	if !primitive {
+		source, err := json.Marshal(record.SourceDocId)
+		if err != nil {
+			return record, fmt.Errorf("failed to encode source doc ID: %w", err)
+		}
+		input = fmt.Sprintf("attested_doc: %s, source_doc: %s, CIDs: %s", attested, source, cids)
+	}
+
+	mutation := fmt.Sprintf(`mutation {
+		create_%s(input: { %s }) {
+			_docID
+		}
+	}`, collectionName, input)
+	if _, err := defra.PostMutation[storedAttestationRecord](ctx, defraNode, mutation); err != nil {
+		return record, fmt.Errorf("Error creating attestation record for %s: %w", record.AttestedDocId, err)
+	}
+
+	record.CIDs = mergeCIDs(nil, record.CIDs)
+	return record, nil
+}
+
+// mergeCIDs appends the CIDs not already present in existing, preserving order
+func mergeCIDs(existing []string, additional []string) []string {
+	merged := make([]string, 0, len(existing)+len(additional))
+	seen := make(map[string]struct{}, len(existing)+len(additional))
+	for _, cids := range [][]string{existing, additional} {
+		for _, cid := range cids {
+			if _, ok := seen[cid]; ok {
+				continue
+			}
+			seen[cid] = struct{}{}
+			merged = append(merged, cid)
+		}
+	}
+	return merged
+}
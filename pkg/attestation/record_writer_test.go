@@ -0,0 +1,53 @@
+package attestation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAttestationRecord(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema(`
+		type User { name: String }
+		type SampleView { name: String }
+	`))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+	err = AddAttestationRecordCollection(ctx, defraNode, "SampleView")
+	require.NoError(t, err)
+
+	source, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_User(input: { name: "Source" }) { _docID } }`)
+	require.NoError(t, err)
+	viewDoc, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Source" }) { _docID } }`)
+	require.NoError(t, err)
+
+	records, err := WriteAttestationRecord(ctx, defraNode, "SampleView", viewDoc.DocId, SourceDoc{Collection: "User", DocId: source.DocId})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, viewDoc.DocId, records[0].AttestedDocId)
+	require.Equal(t, source.DocId, records[0].SourceDocId)
+	require.Len(t, records[0].CIDs, 1)
+
+	verification, err := VerifyAttestationRecord(ctx, defraNode, records[0])
+	require.NoError(t, err)
+	require.True(t, verification.Valid())
+
+	// Writing again after the source has a new version appends to the existing record rather than creating another one
+	_, err = defra.PostMutation[sampleViewDoc](ctx, defraNode, fmt.Sprintf(`mutation { update_User(docID: "%s", input: { name: "Updated" }) { _docID } }`, source.DocId))
+	require.NoError(t, err)
+	records, err = WriteAttestationRecord(ctx, defraNode, "SampleView", viewDoc.DocId, SourceDoc{Collection: "User", DocId: source.DocId})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Len(t, records[0].CIDs, 2)
+
+	stored, err := GetAttestationRecords(ctx, defraNode, "SampleView", []string{viewDoc.DocId})
+	require.NoError(t, err)
+	require.Len(t, stored[viewDoc.DocId], 1)
+	require.ElementsMatch(t, records[0].CIDs, stored[viewDoc.DocId][0].CIDs)
+
+	_, err = WriteAttestationRecord(ctx, defraNode, "SampleView", viewDoc.DocId, SourceDoc{Collection: "User", DocId: "notADoc"})
+	require.Error(t, err)
+}
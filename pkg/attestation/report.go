@@ -0,0 +1,134 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// ViewReport summarises the attestation health of every doc in a View
+type ViewReport struct {
+	View        string    `json:"view"`
+	GeneratedAt time.Time `json:"generated_at"`
+	TotalDocs   int       `json:"total_docs"`
+	// DocsWithRecord is the number of docs with at least one AttestationRecord
+	DocsWithRecord int `json:"docs_with_attestation_record"`
+	// DocsModified is the number of docs with a version height above 1, i.e. docs that have been overwritten
+	DocsModified int `json:"docs_modified"`
+	// DocsWithUntrustedSigners is the number of docs signed by at least one identity outside the trusted set
+	DocsWithUntrustedSigners int         `json:"docs_with_untrusted_signers"`
+	Docs                     []DocReport `json:"docs"`
+}
+
+// DocReport summarises the attestations of a single View doc
+type DocReport struct {
+	DocId                string   `json:"doc_id"`
+	DistinctSigners      int      `json:"distinct_signers"`
+	TrustedSigners       []string `json:"trusted_signers"`
+	UntrustedSigners     []string `json:"untrusted_signers"`
+	MaxHeight            uint     `json:"max_height"`
+	HasAttestationRecord bool     `json:"has_attestation_record"`
+}
+
+// JSON returns the report as indented JSON, e.g. for an ops dashboard
+func (r *ViewReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// Report summarises, for every doc in a View: the number of distinct signers, which of them are trusted,
+// the highest version height and whether an AttestationRecord exists.
+// Without WithTrustedSigners every signer is counted as trusted.
+// The View's AttestationRecord collection must have been added with AddAttestationRecordCollection.
+func Report(ctx context.Context, defraNode *node.Node, viewName string, opts ...Option) (*ViewReport, error) {
+	options := newOptions(opts...)
+
+	query := fmt.Sprintf(`query {
+		%s {
+			_docID
+			_version {
+				cid
+				height
+				signature {
+					type
+					identity
+					value
+				}
+			}
+		}
+	}`, viewName)
+	docs, err := defra.QueryArray[versionedDoc](ctx, defraNode, query)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching versions for %s docs: %w", viewName, err)
+	}
+
+	docIds := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		docIds = append(docIds, doc.DocId)
+	}
+	records, err := GetAttestationRecords(ctx, defraNode, viewName, docIds, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ViewReport{
+		View:        viewName,
+		GeneratedAt: time.Now().UTC(),
+		TotalDocs:   len(docs),
+		Docs:        make([]DocReport, 0, len(docs)),
+	}
+	for _, doc := range docs {
+		docReport := summariseDoc(doc, options.trusted)
+		docReport.HasAttestationRecord = len(records[doc.DocId]) > 0
+
+		if docReport.HasAttestationRecord {
+			report.DocsWithRecord++
+		}
+		if docReport.MaxHeight > 1 {
+			report.DocsModified++
+		}
+		if len(docReport.UntrustedSigners) > 0 {
+			report.DocsWithUntrustedSigners++
+		}
+		report.Docs = append(report.Docs, docReport)
+	}
+	sort.Slice(report.Docs, func(i, j int) bool { return report.Docs[i].DocId < report.Docs[j].DocId })
+
+	return report, nil
+}
+
+func summariseDoc(doc versionedDoc, trusted *TrustedSigners) DocReport {
+	docReport := DocReport{
+		DocId:            doc.DocId,
+		TrustedSigners:   []string{},
+		UntrustedSigners: []string{},
+	}
+
+	signers := map[string]bool{}
+	for _, version := range doc.Version {
+		if version.Height > docReport.MaxHeight {
+			docReport.MaxHeight = version.Height
+		}
+		identity := normalizeIdentity(version.Signature.Identity)
+		if identity == "" {
+			continue
+		}
+		signers[identity] = trusted.IsTrusted(identity)
+	}
+
+	for identity, isTrusted := range signers {
+		if isTrusted {
+			docReport.TrustedSigners = append(docReport.TrustedSigners, identity)
+		} else {
+			docReport.UntrustedSigners = append(docReport.UntrustedSigners, identity)
+		}
+	}
+	sort.Strings(docReport.TrustedSigners)
+	sort.Strings(docReport.UntrustedSigners)
+	docReport.DistinctSigners = len(signers)
+	return docReport
+}
@@ -0,0 +1,79 @@
+package attestation
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummariseDoc(t *testing.T) {
+	doc := versionedDoc{
+		DocId: "doc",
+		Version: []Version{
+			testVersion("a", 1, "IndexerA"),
+			testVersion("b", 1, "indexerB"),
+			testVersion("c", 2, "attacker"),
+			testVersion("d", 3, "attacker"),
+		},
+	}
+
+	summary := summariseDoc(doc, NewTrustedSigners("indexerA", "indexerB"))
+	require.Equal(t, 3, summary.DistinctSigners)
+	require.Equal(t, []string{"indexera", "indexerb"}, summary.TrustedSigners)
+	require.Equal(t, []string{"attacker"}, summary.UntrustedSigners)
+	require.Equal(t, uint(3), summary.MaxHeight)
+
+	summary = summariseDoc(doc, nil)
+	require.Len(t, summary.TrustedSigners, 3)
+	require.Empty(t, summary.UntrustedSigners)
+}
+
+func TestReport(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema(`
+		type User { name: String }
+		type SampleView { name: String }
+	`))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+	err = AddAttestationRecordCollection(ctx, defraNode, "SampleView")
+	require.NoError(t, err)
+
+	source, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_User(input: { name: "Source" }) { _docID } }`)
+	require.NoError(t, err)
+	attested, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Attested" }) { _docID } }`)
+	require.NoError(t, err)
+	modified, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Modified" }) { _docID } }`)
+	require.NoError(t, err)
+	_, err = defra.PostMutation[sampleViewDoc](ctx, defraNode, fmt.Sprintf(`mutation { update_SampleView(docID: "%s", input: { name: "Overwritten" }) { _docID } }`, modified.DocId))
+	require.NoError(t, err)
+	_, err = WriteAttestationRecord(ctx, defraNode, "SampleView", attested.DocId, SourceDoc{Collection: "User", DocId: source.DocId})
+	require.NoError(t, err)
+
+	report, err := Report(ctx, defraNode, "SampleView", WithTrustedSigners(NewTrustedSigners("someOtherIndexer")))
+	require.NoError(t, err)
+	require.Equal(t, "SampleView", report.View)
+	require.Equal(t, 2, report.TotalDocs)
+	require.Equal(t, 1, report.DocsWithRecord)
+	require.Equal(t, 1, report.DocsModified)
+	require.Equal(t, 2, report.DocsWithUntrustedSigners)
+
+	docs := map[string]DocReport{}
+	for _, doc := range report.Docs {
+		docs[doc.DocId] = doc
+	}
+	require.True(t, docs[attested.DocId].HasAttestationRecord)
+	require.Equal(t, uint(1), docs[attested.DocId].MaxHeight)
+	require.Equal(t, 1, docs[attested.DocId].DistinctSigners)
+	require.False(t, docs[modified.DocId].HasAttestationRecord)
+	require.Equal(t, uint(2), docs[modified.DocId].MaxHeight)
+
+	data, err := report.JSON()
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, float64(2), decoded["total_docs"])
+}
@@ -0,0 +1,112 @@
+package attestation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// HasDocBeenModified reports whether any of a document's versions is an update (height > 1) rather than a create.
+// View documents are written once by each indexer, so an update indicates that a writer has overwritten the original data.
+func HasDocBeenModified(versions []Version) bool {
+	for _, version := range versions {
+		if version.Height > uint(1) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTampered reports whether a document's history shows an update, or a version signed by an identity outside the trusted set.
+// A nil trusted set only checks for updates.
+func IsTampered(versions []Version, trusted *TrustedSigners) bool {
+	if HasDocBeenModified(versions) {
+		return true
+	}
+	for _, version := range versions {
+		if !trusted.IsTrusted(version.Signature.Identity) {
+			return true
+		}
+	}
+	return false
+}
+
+// LastKnownGoodVersion returns the highest version whose height has been signed by at least minimumAttestations distinct trusted identities.
+// A single writer (e.g. one padding `_version` with updates) can therefore only move the last known good version if it meets the threshold on its own.
+// Returns false if no version satisfies the policy.
+func LastKnownGoodVersion(versions []Version, trusted *TrustedSigners, minimumAttestations int) (Version, bool) {
+	if minimumAttestations < 1 {
+		minimumAttestations = 1
+	}
+
+	versionsByHeight := map[uint][]Version{}
+	for _, version := range versions {
+		if trusted.IsTrusted(version.Signature.Identity) {
+			versionsByHeight[version.Height] = append(versionsByHeight[version.Height], version)
+		}
+	}
+
+	heights := make([]uint, 0, len(versionsByHeight))
+	for height := range versionsByHeight {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+
+	for _, height := range heights {
+		candidates := versionsByHeight[height]
+		if CountTrustedAttestations(candidates, trusted) < minimumAttestations {
+			continue
+		}
+		// Pick deterministically so that every reader rolls back to the same CID
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].CID < candidates[j].CID })
+		return candidates[0], true
+	}
+	return Version{}, false
+}
+
+// QueryLastKnownGood returns the last known good state of a document, as selected by LastKnownGoodVersion.
+// If the document has not been tampered with, its current state is returned; otherwise it is fetched with a time-travel (`cid:`) query.
+// selection is the GraphQL selection set to return, e.g. "name friends".
+func QueryLastKnownGood[T any](ctx context.Context, defraNode *node.Node, collectionName string, docId string, selection string, minimumAttestations int, trusted *TrustedSigners) (T, error) {
+	var result T
+
+	docs, err := getDocVersions(ctx, defraNode, collectionName, []string{docId})
+	if err != nil {
+		return result, err
+	}
+	if len(docs) == 0 {
+		return result, fmt.Errorf("document %s not found in %s", docId, collectionName)
+	}
+	versions := docs[0].Version
+
+	goodVersion, ok := LastKnownGoodVersion(versions, trusted, minimumAttestations)
+	if !ok {
+		return result, fmt.Errorf("document %s in %s has no version meeting the attestation policy", docId, collectionName)
+	}
+
+	var query string
+	variables := map[string]any{"docID": []string{docId}}
+	if !IsTampered(versions, trusted) {
+		query = fmt.Sprintf(`query($docID: [String!]) {
+			%s (docID: $docID) {
+				%s
+			}
+		}`, collectionName, selection)
+	} else {
+		query = fmt.Sprintf(`query($cid: String, $docID: [String!]) {
+			%s (cid: $cid, docID: $docID) {
+				%s
+			}
+		}`, collectionName, selection)
+		variables["cid"] = goodVersion.CID
+	}
+
+	result, err = defra.QuerySingleWithVariables[T](ctx, defraNode, query, variables)
+	if err != nil {
+		return result, fmt.Errorf("Error fetching last known good version %s of %s: %w", goodVersion.CID, docId, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package attestation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/stretchr/testify/require"
+)
+
+func testVersion(cid string, height uint, identity string) Version {
+	return Version{CID: cid, Height: height, Signature: Signature{Identity: identity}}
+}
+
+func TestIsTampered(t *testing.T) {
+	created := []Version{testVersion("a", 1, "indexerA"), testVersion("b", 1, "indexerB")}
+	require.False(t, HasDocBeenModified(created))
+	require.False(t, IsTampered(created, nil))
+	require.False(t, IsTampered(created, NewTrustedSigners("indexerA", "indexerB")))
+	require.True(t, IsTampered(created, NewTrustedSigners("indexerA")))
+
+	updated := append(created, testVersion("c", 2, "indexerA"))
+	require.True(t, HasDocBeenModified(updated))
+	require.True(t, IsTampered(updated, nil))
+}
+
+func TestLastKnownGoodVersion(t *testing.T) {
+	// Three indexers create the doc, then a malicious writer pads the history with updates
+	versions := []Version{
+		testVersion("create-c", 1, "indexerC"),
+		testVersion("create-a", 1, "indexerA"),
+		testVersion("create-b", 1, "indexerB"),
+		testVersion("update-1", 2, "attacker"),
+		testVersion("update-2", 3, "attacker"),
+		testVersion("update-3", 4, "attacker"),
+	}
+
+	good, ok := LastKnownGoodVersion(versions, nil, 2)
+	require.True(t, ok)
+	require.Equal(t, "create-a", good.CID)
+
+	// With a threshold of 1 and no allowlist the attacker's latest update wins
+	good, ok = LastKnownGoodVersion(versions, nil, 1)
+	require.True(t, ok)
+	require.Equal(t, "update-3", good.CID)
+
+	// Unless the attacker isn't trusted
+	good, ok = LastKnownGoodVersion(versions, NewTrustedSigners("indexerB"), 1)
+	require.True(t, ok)
+	require.Equal(t, "create-b", good.CID)
+
+	_, ok = LastKnownGoodVersion(versions, nil, 4)
+	require.False(t, ok)
+}
+
+func TestQueryLastKnownGood(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+
+	created, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Original" }) { _docID name } }`)
+	require.NoError(t, err)
+
+	doc, err := QueryLastKnownGood[sampleViewDoc](ctx, defraNode, "SampleView", created.DocId, "_docID name", 1, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Original", doc.Name)
+
+	_, err = defra.PostMutation[sampleViewDoc](ctx, defraNode, fmt.Sprintf(`mutation { update_SampleView(docID: "%s", input: { name: "Tampered" }) { name } }`, created.DocId))
+	require.NoError(t, err)
+
+	// Both versions are signed by our node alone, so with a threshold of 1 the update is accepted...
+	doc, err = QueryLastKnownGood[sampleViewDoc](ctx, defraNode, "SampleView", created.DocId, "_docID name", 1, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Tampered", doc.Name)
+
+	// ...but no version meets a threshold of 2
+	_, err = QueryLastKnownGood[sampleViewDoc](ctx, defraNode, "SampleView", created.DocId, "_docID name", 2, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,139 @@
+package attestation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/shinzonetwork/app-sdk/pkg/signer"
+)
+
+// TrustedSigners is an allowlist of indexer identities (public keys) whose signatures count towards a document's attestations.
+// A nil *TrustedSigners trusts every identity.
+type TrustedSigners struct {
+	identities map[string]struct{}
+}
+
+// SignedTrustedSignersFile is the on-disk format of a trusted signer list.
+// Signature is a hex-encoded secp256k1 signature, made by the list's authority, over TrustedSignersMessage(Signers).
+type SignedTrustedSignersFile struct {
+	Signers   []string `json:"signers"`
+	Signature string   `json:"signature"`
+}
+
+// NewTrustedSigners creates an allowlist containing the given identities
+func NewTrustedSigners(identities ...string) *TrustedSigners {
+	trusted := &TrustedSigners{identities: map[string]struct{}{}}
+	for _, identity := range identities {
+		trusted.Add(identity)
+	}
+	return trusted
+}
+
+// Add adds an identity to the allowlist
+func (t *TrustedSigners) Add(identity string) {
+	identity = normalizeIdentity(identity)
+	if identity == "" {
+		return
+	}
+	t.identities[identity] = struct{}{}
+}
+
+// IsTrusted reports whether signatures by the given identity should be counted
+func (t *TrustedSigners) IsTrusted(identity string) bool {
+	if t == nil {
+		return identity != ""
+	}
+	_, ok := t.identities[normalizeIdentity(identity)]
+	return ok
+}
+
+// Identities returns the trusted identities in sorted order
+func (t *TrustedSigners) Identities() []string {
+	if t == nil {
+		return nil
+	}
+	identities := make([]string, 0, len(t.identities))
+	for identity := range t.identities {
+		identities = append(identities, identity)
+	}
+	sort.Strings(identities)
+	return identities
+}
+
+// LoadTrustedSignersFromConfig builds the allowlist from `shinzo.trusted_signers` and, if configured, the signed `shinzo.trusted_signers_file`.
+// Returns nil (trust everyone) if neither is configured.
+func LoadTrustedSignersFromConfig(cfg *config.Config) (*TrustedSigners, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	if len(cfg.Shinzo.TrustedSigners) == 0 && cfg.Shinzo.TrustedSignersFile == "" {
+		return nil, nil
+	}
+
+	trusted := NewTrustedSigners(cfg.Shinzo.TrustedSigners...)
+	if cfg.Shinzo.TrustedSignersFile != "" {
+		fromFile, err := LoadTrustedSignersFromFile(cfg.Shinzo.TrustedSignersFile, cfg.Shinzo.TrustedSignersAuthority)
+		if err != nil {
+			return nil, err
+		}
+		for _, identity := range fromFile.Identities() {
+			trusted.Add(identity)
+		}
+	}
+	return trusted, nil
+}
+
+// LoadTrustedSignersFromFile reads a SignedTrustedSignersFile and verifies its signature against the authority's public key.
+func LoadTrustedSignersFromFile(path string, authorityPublicKey string) (*TrustedSigners, error) {
+	if authorityPublicKey == "" {
+		return nil, fmt.Errorf("an authority public key is required to verify trusted signers file %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted signers file: %w", err)
+	}
+
+	var file SignedTrustedSignersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse trusted signers file: %w", err)
+	}
+
+	err = signer.VerifyDefraSignature(authorityPublicKey, TrustedSignersMessage(file.Signers), file.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("trusted signers file %s is not signed by %s: %w", path, authorityPublicKey, err)
+	}
+
+	return NewTrustedSigners(file.Signers...), nil
+}
+
+// TrustedSignersMessage returns the canonical message that an authority signs to produce a SignedTrustedSignersFile.
+// Signers are normalized, de-duplicated and sorted so that the message does not depend on list order.
+func TrustedSignersMessage(signers []string) string {
+	return strings.Join(NewTrustedSigners(signers...).Identities(), "\n")
+}
+
+// CountTrustedAttestations returns the number of distinct trusted identities that signed the given versions.
+// Repeated signatures by the same identity (e.g. padding `_version` with empty updates) are only counted once.
+func CountTrustedAttestations(versions []Version, trusted *TrustedSigners) int {
+	return len(trustedIdentities(versions, trusted))
+}
+
+// trustedIdentities returns the set of distinct trusted identities that signed the given versions
+func trustedIdentities(versions []Version, trusted *TrustedSigners) map[string]struct{} {
+	identities := map[string]struct{}{}
+	for _, version := range versions {
+		if trusted.IsTrusted(version.Signature.Identity) {
+			identities[normalizeIdentity(version.Signature.Identity)] = struct{}{}
+		}
+	}
+	return identities
+}
+
+func normalizeIdentity(identity string) string {
+	return strings.ToLower(strings.TrimSpace(identity))
+}
@@ -0,0 +1,96 @@
+package attestation
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/shinzonetwork/app-sdk/pkg/signer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountTrustedAttestations(t *testing.T) {
+	versions := []Version{
+		{CID: "a", Signature: Signature{Identity: "TrustedA"}},
+		{CID: "b", Signature: Signature{Identity: "trusteda"}}, // Same identity padding _version with another update
+		{CID: "c", Signature: Signature{Identity: "trustedB"}},
+		{CID: "d", Signature: Signature{Identity: "untrusted"}},
+		{CID: "e", Signature: Signature{Identity: ""}},
+	}
+
+	require.Equal(t, 2, CountTrustedAttestations(versions, NewTrustedSigners("trustedA", "TRUSTEDB")))
+	require.Equal(t, 0, CountTrustedAttestations(versions, NewTrustedSigners()))
+	require.Equal(t, 3, CountTrustedAttestations(versions, nil)) // nil trusts every (non-empty) identity
+}
+
+func TestTrustedSignersMessageIsOrderIndependent(t *testing.T) {
+	require.Equal(t, TrustedSignersMessage([]string{"b", "a", "A"}), TrustedSignersMessage([]string{"a", "b"}))
+}
+
+func TestLoadTrustedSignersFromFile(t *testing.T) {
+	testConfig := &config.Config{
+		DefraDB: config.DefraDBConfig{
+			Url:           "http://localhost:0",
+			KeyringSecret: "test-secret",
+			Store: config.DefraStoreConfig{
+				Path: t.TempDir(),
+			},
+		},
+		Logger: config.LoggerConfig{
+			Development: true,
+		},
+	}
+	defraNode, err := defra.StartDefraInstance(testConfig, defra.NewSchemaApplierFromProvidedSchema("type User { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(t.Context())
+
+	authority, err := signer.GetDefraPublicKey(defraNode, testConfig)
+	require.NoError(t, err)
+
+	signers := []string{"indexerA", "indexerB"}
+	signature, err := signer.SignWithDefraKeys(TrustedSignersMessage(signers), defraNode, testConfig)
+	require.NoError(t, err)
+
+	writeFile := func(file SignedTrustedSignersFile) string {
+		data, err := json.Marshal(file)
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "trusted_signers.json")
+		require.NoError(t, os.WriteFile(path, data, 0644))
+		return path
+	}
+
+	path := writeFile(SignedTrustedSignersFile{Signers: signers, Signature: signature})
+	trusted, err := LoadTrustedSignersFromFile(path, authority)
+	require.NoError(t, err)
+	require.True(t, trusted.IsTrusted("indexerA"))
+	require.True(t, trusted.IsTrusted("indexerB"))
+	require.False(t, trusted.IsTrusted("indexerC"))
+
+	// Config-provided signers are merged with the signed file
+	cfg := &config.Config{Shinzo: config.ShinzoConfig{
+		TrustedSigners:          []string{"indexerC"},
+		TrustedSignersFile:      path,
+		TrustedSignersAuthority: authority,
+	}}
+	trusted, err = LoadTrustedSignersFromConfig(cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"indexera", "indexerb", "indexerc"}, trusted.Identities())
+
+	// Adding a signer invalidates the authority's signature
+	tampered := writeFile(SignedTrustedSignersFile{Signers: append(signers, "attacker"), Signature: signature})
+	_, err = LoadTrustedSignersFromFile(tampered, authority)
+	require.Error(t, err)
+
+	_, err = LoadTrustedSignersFromFile(path, "")
+	require.Error(t, err)
+}
+
+func TestLoadTrustedSignersFromConfigWithoutSigners(t *testing.T) {
+	trusted, err := LoadTrustedSignersFromConfig(&config.Config{})
+	require.NoError(t, err)
+	require.Nil(t, trusted)
+	require.True(t, trusted.IsTrusted("anyone"))
+}
@@ -0,0 +1,243 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/shinzonetwork/app-sdk/pkg/signer"
+	"github.com/sourcenetwork/corekv/blockstore"
+	"github.com/sourcenetwork/corekv/namespace"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// Signature types written by DefraDB into a block's signature header
+const (
+	SignatureTypeSecp256k1 = "ES256K"
+	SignatureTypeEd25519   = "EdDSA"
+)
+
+// DefraDB stores blocks in its rootstore under this namespace
+const blockStoreNamespace = byte('b')
+
+var (
+	ErrCIDMismatch       = errors.New("block bytes do not hash to the claimed CID")
+	ErrVersionUnsigned   = errors.New("block is not signed")
+	ErrSignatureMismatch = errors.New("block signature does not match the claimed signature")
+	ErrInvalidSignature  = errors.New("block signature is invalid")
+)
+
+// VerifyVersion independently proves that a `_version` entry was signed by the identity it claims, without trusting DefraDB's reporting.
+// It loads the commit block from the local blockstore, recomputes its CID from the block bytes,
+// and verifies the block's signature with the algorithm named in its signature header.
+func VerifyVersion(ctx context.Context, defraNode *node.Node, version Version) error {
+	blockCid, err := cid.Decode(version.CID)
+	if err != nil {
+		return fmt.Errorf("invalid version CID %s: %w", version.CID, err)
+	}
+
+	block, err := loadVerifiedBlock(ctx, defraNode, blockCid)
+	if err != nil {
+		return err
+	}
+
+	signatureCid, err := getSignatureLink(block)
+	if err != nil {
+		return fmt.Errorf("%s: %w", version.CID, err)
+	}
+	signatureBlock, err := loadVerifiedBlock(ctx, defraNode, signatureCid)
+	if err != nil {
+		return err
+	}
+	signatureType, identity, value, err := readSignatureBlock(signatureBlock)
+	if err != nil {
+		return fmt.Errorf("%s has a malformed signature block: %w", version.CID, err)
+	}
+
+	// The signature stored in the block must be the one DefraDB reported to us
+	if identity != version.Signature.Identity || (version.Signature.Type != "" && signatureType != version.Signature.Type) {
+		return fmt.Errorf("%s claims %s signature by %s but block is signed with %s by %s: %w",
+			version.CID, version.Signature.Type, version.Signature.Identity, signatureType, identity, ErrSignatureMismatch)
+	}
+
+	return verifyBlockSignature(version.CID, block, signatureType, identity, value)
+}
+
+// VerifyVersions runs VerifyVersion on each version and returns the versions that passed, along with the reason each of the others failed (keyed by CID)
+func VerifyVersions(ctx context.Context, defraNode *node.Node, versions []Version) ([]Version, map[string]error) {
+	verified := make([]Version, 0, len(versions))
+	failures := map[string]error{}
+	for _, version := range versions {
+		if err := VerifyVersion(ctx, defraNode, version); err != nil {
+			failures[version.CID] = err
+			continue
+		}
+		verified = append(verified, version)
+	}
+	return verified, failures
+}
+
+// loadVerifiedBlock reads a block's raw bytes from the node's blockstore, checks that they hash to the requested CID, and decodes them
+func loadVerifiedBlock(ctx context.Context, defraNode *node.Node, blockCid cid.Cid) (datamodel.Node, error) {
+	raw, err := loadRawBlock(ctx, defraNode, blockCid)
+	if err != nil {
+		return nil, err
+	}
+	return decodeVerifiedBlock(blockCid, raw)
+}
+
+// loadRawBlock reads a block's raw bytes from the node's blockstore
+func loadRawBlock(ctx context.Context, defraNode *node.Node, blockCid cid.Cid) ([]byte, error) {
+	if defraNode == nil || defraNode.DB == nil {
+		return nil, fmt.Errorf("defra node cannot be nil")
+	}
+	// Blocks are read straight from the rootstore, which a remote DefraDB doesn't expose
+	if defra.IsRemote(defraNode) {
+		return nil, fmt.Errorf("failed to load block %s: %w", blockCid, defra.ErrNotSupportedRemotely)
+	}
+	if defraNode.DB.Rootstore() == nil {
+		return nil, fmt.Errorf("failed to load block %s: node has no rootstore", blockCid)
+	}
+
+	store := blockstore.NewBlockstore(namespace.Wrap(defraNode.DB.Rootstore(), []byte{blockStoreNamespace}))
+	rawBlock, err := store.Get(ctx, blockCid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load block %s: %w", blockCid, err)
+	}
+	return rawBlock.RawData(), nil
+}
+
+// decodeVerifiedBlock checks that a block's raw bytes hash to the given CID, and decodes them
+func decodeVerifiedBlock(blockCid cid.Cid, raw []byte) (datamodel.Node, error) {
+	computed, err := blockCid.Prefix().Sum(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash block %s: %w", blockCid, err)
+	}
+	if !computed.Equals(blockCid) {
+		return nil, fmt.Errorf("%s hashes to %s: %w", blockCid, computed, ErrCIDMismatch)
+	}
+
+	builder := basicnode.Prototype.Any.NewBuilder()
+	if err := dagcbor.Decode(builder, bytes.NewReader(raw)); err != nil {
+		return nil, fmt.Errorf("failed to decode block %s: %w", blockCid, err)
+	}
+	return builder.Build(), nil
+}
+
+// getSignatureLink returns the CID of the signature block linked from a commit block
+func getSignatureLink(block datamodel.Node) (cid.Cid, error) {
+	signatureLink, err := block.LookupByString("signature")
+	if err != nil || signatureLink.IsNull() {
+		return cid.Undef, ErrVersionUnsigned
+	}
+	link, err := signatureLink.AsLink()
+	if err != nil {
+		return cid.Undef, fmt.Errorf("malformed signature link: %w", err)
+	}
+	signatureCid, ok := link.(cidlink.Link)
+	if !ok {
+		return cid.Undef, fmt.Errorf("unsupported signature link type %T", link)
+	}
+	return signatureCid.Cid, nil
+}
+
+// verifyBlockSignature checks that value is a valid signature by identity over the block, as DefraDB produces it
+func verifyBlockSignature(blockCid string, block datamodel.Node, signatureType string, identity string, value []byte) error {
+	// DefraDB signs the block's bytes before the signature link is added to it
+	signedBytes, err := encodeWithoutField(block, "signature")
+	if err != nil {
+		return fmt.Errorf("failed to re-encode block %s: %w", blockCid, err)
+	}
+
+	switch signatureType {
+	case SignatureTypeSecp256k1:
+		err = signer.VerifyDefraSignature(identity, string(signedBytes), hex.EncodeToString(value))
+	case SignatureTypeEd25519:
+		err = signer.VerifyP2PSignature(identity, string(signedBytes), hex.EncodeToString(value))
+	default:
+		return fmt.Errorf("%s is signed with unsupported signature type %s: %w", blockCid, signatureType, ErrInvalidSignature)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", blockCid, ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+// readSignatureBlock extracts the fields of a DefraDB signature block: { header: { type, identity }, value }
+func readSignatureBlock(signatureBlock datamodel.Node) (string, string, []byte, error) {
+	header, err := signatureBlock.LookupByString("header")
+	if err != nil {
+		return "", "", nil, err
+	}
+	typeNode, err := header.LookupByString("type")
+	if err != nil {
+		return "", "", nil, err
+	}
+	signatureType, err := typeNode.AsString()
+	if err != nil {
+		return "", "", nil, err
+	}
+	identityNode, err := header.LookupByString("identity")
+	if err != nil {
+		return "", "", nil, err
+	}
+	identity, err := identityNode.AsBytes()
+	if err != nil {
+		return "", "", nil, err
+	}
+	valueNode, err := signatureBlock.LookupByString("value")
+	if err != nil {
+		return "", "", nil, err
+	}
+	value, err := valueNode.AsBytes()
+	if err != nil {
+		return "", "", nil, err
+	}
+	return signatureType, string(identity), value, nil
+}
+
+// encodeWithoutField re-encodes a dag-cbor map with one of its keys removed.
+// dag-cbor encoding is canonical, so this reproduces the bytes the block had before that field was set.
+func encodeWithoutField(mapNode datamodel.Node, field string) ([]byte, error) {
+	builder := basicnode.Prototype.Map.NewBuilder()
+	assembler, err := builder.BeginMap(mapNode.Length())
+	if err != nil {
+		return nil, err
+	}
+	for iterator := mapNode.MapIterator(); !iterator.Done(); {
+		key, value, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		keyString, err := key.AsString()
+		if err != nil {
+			return nil, err
+		}
+		if keyString == field {
+			continue
+		}
+		if err := assembler.AssembleKey().AssignString(keyString); err != nil {
+			return nil, err
+		}
+		if err := assembler.AssembleValue().AssignNode(value); err != nil {
+			return nil, err
+		}
+	}
+	if err := assembler.Finish(); err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	if err := dagcbor.Encode(builder.Build(), &buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,134 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// RecordVerification reports how the CIDs listed in an AttestationRecord relate to the source document's commit DAG
+type RecordVerification struct {
+	SourceDocId string `json:"source_doc"`
+	// Matched CIDs belong to the source doc's history and carry a valid signature
+	Matched []string `json:"matched"`
+	// Unsigned CIDs belong to the source doc's history but have no valid signature
+	Unsigned []string `json:"unsigned"`
+	// Missing CIDs could not be found locally
+	Missing []string `json:"missing"`
+	// Foreign CIDs are commits of some other document
+	Foreign []string `json:"foreign"`
+}
+
+// Valid reports whether every CID in the record was matched against the source doc's history
+func (v RecordVerification) Valid() bool {
+	return len(v.Matched) > 0 && len(v.Unsigned) == 0 && len(v.Missing) == 0 && len(v.Foreign) == 0
+}
+
+// VerifyAttestationRecord walks the `_commits` of the record's source document and checks that every CID in the record
+// is part of that document's history and is signed. Errors that stop a signature from being checked, e.g. on a remote
+// node (ErrNotSupportedRemotely), are returned rather than reported as unsigned CIDs.
+// Records for primitive collections use a condensed schema without `source_doc`; for those the attested doc is the source doc.
+func VerifyAttestationRecord(ctx context.Context, defraNode *node.Node, record AttestationRecord) (RecordVerification, error) {
+	sourceDocId := record.sourceDocId()
+	verification := RecordVerification{
+		SourceDocId: sourceDocId,
+		Matched:     []string{},
+		Unsigned:    []string{},
+		Missing:     []string{},
+		Foreign:     []string{},
+	}
+	if sourceDocId == "" {
+		return verification, fmt.Errorf("attestation record has neither a source_doc nor an attested_doc")
+	}
+
+	history, err := getDocCommits(ctx, defraNode, sourceDocId)
+	if err != nil {
+		return verification, err
+	}
+	commitsByCid := make(map[string]commit, len(history))
+	for _, c := range history {
+		commitsByCid[c.CID] = c
+	}
+
+	for _, recordCid := range record.CIDs {
+		c, ok := commitsByCid[recordCid]
+		if !ok {
+			docId, found, err := getCommitDocId(ctx, defraNode, recordCid)
+			if err != nil {
+				return verification, err
+			}
+			if found && docId != sourceDocId {
+				verification.Foreign = append(verification.Foreign, recordCid)
+			} else {
+				verification.Missing = append(verification.Missing, recordCid)
+			}
+			continue
+		}
+
+		if c.Signature.Identity == "" {
+			verification.Unsigned = append(verification.Unsigned, recordCid)
+			continue
+		}
+		err := VerifyVersion(ctx, defraNode, Version{CID: c.CID, Height: c.Height, Signature: c.Signature})
+		if errors.Is(err, ErrVersionUnsigned) || errors.Is(err, ErrInvalidSignature) || errors.Is(err, ErrSignatureMismatch) {
+			verification.Unsigned = append(verification.Unsigned, recordCid)
+			continue
+		}
+		if err != nil {
+			// The signature couldn't be checked at all, e.g. remotely or on a read failure, which says nothing about the record
+			return verification, fmt.Errorf("failed to verify %s: %w", recordCid, err)
+		}
+		verification.Matched = append(verification.Matched, recordCid)
+	}
+
+	return verification, nil
+}
+
+// getDocCommits returns every commit (composite and field level) in a document's history
+func getDocCommits(ctx context.Context, defraNode *node.Node, docId string) ([]commit, error) {
+	query := `query($docID: ID) {
+		_commits(docID: $docID) {
+			cid
+			docID
+			height
+			signature {
+				type
+				identity
+				value
+			}
+		}
+	}`
+	commits, err := defra.QueryArrayWithVariables[commit](ctx, defraNode, query, map[string]any{"docID": docId})
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching commits for doc %s: %w", docId, err)
+	}
+	return commits, nil
+}
+
+// getCommitDocId returns the docID of the commit with the given CID, and whether the commit was found at all
+func getCommitDocId(ctx context.Context, defraNode *node.Node, commitCid string) (string, bool, error) {
+	if !isValidCid(commitCid) {
+		return "", false, nil
+	}
+
+	query := `query($cid: ID) {
+		_commits(cid: $cid) {
+			cid
+			docID
+		}
+	}`
+	commits, err := defra.QueryArrayWithVariables[commit](ctx, defraNode, query, map[string]any{"cid": commitCid})
+	if defra.IsCommitNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("Error fetching commit %s: %w", commitCid, err)
+	}
+	if len(commits) == 0 {
+		return "", false, nil
+	}
+	return commits[0].DocId, true, nil
+}
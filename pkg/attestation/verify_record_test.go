@@ -0,0 +1,74 @@
+package attestation
+
+import (
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAttestationRecord(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type User { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+
+	source, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_User(input: { name: "Source" }) { _docID } }`)
+	require.NoError(t, err)
+	other, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_User(input: { name: "Other" }) { _docID } }`)
+	require.NoError(t, err)
+
+	docs, err := getDocVersions(ctx, defraNode, "User", []string{source.DocId, other.DocId})
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	versionCids := map[string]string{}
+	for _, doc := range docs {
+		require.NotEmpty(t, doc.Version)
+		versionCids[doc.DocId] = doc.Version[0].CID
+	}
+
+	unknownCid := "bafyreigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"
+	record := AttestationRecord{
+		AttestedDocId: "someViewDoc",
+		SourceDocId:   source.DocId,
+		CIDs:          []string{versionCids[source.DocId], versionCids[other.DocId], unknownCid, "notACid"},
+	}
+
+	verification, err := VerifyAttestationRecord(ctx, defraNode, record)
+	require.NoError(t, err)
+	require.Equal(t, source.DocId, verification.SourceDocId)
+	require.Equal(t, []string{versionCids[source.DocId]}, verification.Matched)
+	require.Equal(t, []string{versionCids[other.DocId]}, verification.Foreign)
+	require.ElementsMatch(t, []string{unknownCid, "notACid"}, verification.Missing)
+	require.Empty(t, verification.Unsigned)
+	require.False(t, verification.Valid())
+
+	record.CIDs = []string{versionCids[source.DocId]}
+	verification, err = VerifyAttestationRecord(ctx, defraNode, record)
+	require.NoError(t, err)
+	require.True(t, verification.Valid())
+
+	// Primitive attestation records have no source_doc; the attested doc is the source
+	verification, err = VerifyAttestationRecord(ctx, defraNode, AttestationRecord{AttestedDocId: source.DocId, CIDs: record.CIDs})
+	require.NoError(t, err)
+	require.True(t, verification.Valid())
+}
+
+func TestVerifyAttestationRecordRemotely(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type User { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+
+	source, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_User(input: { name: "Source" }) { _docID } }`)
+	require.NoError(t, err)
+	docs, err := getDocVersions(ctx, defraNode, "User", []string{source.DocId})
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+
+	// The signature can't be checked over HTTP, which mustn't be mistaken for the commit being unsigned
+	remote, err := defra.ConnectToRemote(ctx, defraNode.APIURL)
+	require.NoError(t, err)
+	_, err = VerifyAttestationRecord(ctx, remote, AttestationRecord{AttestedDocId: source.DocId, CIDs: []string{docs[0].Version[0].CID}})
+	require.ErrorIs(t, err, defra.ErrNotSupportedRemotely)
+}
@@ -0,0 +1,65 @@
+package attestation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyVersion(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+
+	created, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Original" }) { _docID name } }`)
+	require.NoError(t, err)
+	_, err = defra.PostMutation[sampleViewDoc](ctx, defraNode, fmt.Sprintf(`mutation { update_SampleView(docID: "%s", input: { name: "Updated" }) { name } }`, created.DocId))
+	require.NoError(t, err)
+
+	docs, err := getDocVersions(ctx, defraNode, "SampleView", []string{created.DocId})
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	require.Len(t, docs[0].Version, 2)
+
+	for _, version := range docs[0].Version {
+		require.NoError(t, VerifyVersion(ctx, defraNode, version))
+	}
+
+	verified, failures := VerifyVersions(ctx, defraNode, docs[0].Version)
+	require.Len(t, verified, 2)
+	require.Empty(t, failures)
+
+	// A version claiming to be signed by someone else is rejected
+	forged := docs[0].Version[0]
+	forged.Signature.Identity = docs[0].Version[0].Signature.Identity + "00"
+	require.ErrorIs(t, VerifyVersion(ctx, defraNode, forged), ErrSignatureMismatch)
+
+	// As is a version we don't have the block for
+	unknown := docs[0].Version[0]
+	unknown.CID = "bafyreigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"
+	require.Error(t, VerifyVersion(ctx, defraNode, unknown))
+
+	verified, failures = VerifyVersions(ctx, defraNode, []Version{docs[0].Version[0], forged})
+	require.Len(t, verified, 1)
+	require.Len(t, failures, 1)
+}
+
+func TestVerifyVersionRemotely(t *testing.T) {
+	ctx := t.Context()
+	defraNode, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, defra.NewSchemaApplierFromProvidedSchema("type SampleView { name: String }"))
+	require.NoError(t, err)
+	defer defraNode.Close(ctx)
+	created, err := defra.PostMutation[sampleViewDoc](ctx, defraNode, `mutation { create_SampleView(input: { name: "Original" }) { _docID name } }`)
+	require.NoError(t, err)
+	docs, err := getDocVersions(ctx, defraNode, "SampleView", []string{created.DocId})
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+
+	// Blocks can't be read over HTTP, so verification against a remote DefraDB is refused rather than reported as a failure to find them
+	remote, err := defra.ConnectToRemote(ctx, defraNode.APIURL)
+	require.NoError(t, err)
+	require.ErrorIs(t, VerifyVersion(ctx, remote, docs[0].Version[0]), defra.ErrNotSupportedRemotely)
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
@@ -16,24 +19,125 @@ type Config struct {
 	Logger  LoggerConfig  `yaml:"logger"`
 }
 
+// DefraDB modes
+const (
+	// DefraModeEmbedded starts a DefraDB node in-process, serving its API at Url. This is the default.
+	DefraModeEmbedded = "embedded"
+	// DefraModeRemote connects to a DefraDB running as a separate process, serving its API at Url
+	DefraModeRemote = "remote"
+)
+
 type DefraDBConfig struct {
-	Url           string           `yaml:"url"`
+	// Mode is either DefraModeEmbedded (the default if empty) or DefraModeRemote
+	Mode string `yaml:"mode"`
+	// Url is the address the HTTP API binds to, used as-is. Leave it on loopback unless the API must be reachable
+	// from other machines.
+	Url string `yaml:"url"`
+	// AnnounceUrl is the URL other machines should use to reach the HTTP API, if it differs from Url (e.g. behind NAT
+	// or a proxy)
+	AnnounceUrl   string           `yaml:"announce_url"`
 	KeyringSecret string           `yaml:"keyring_secret"`
 	P2P           DefraP2PConfig   `yaml:"p2p"`
 	Store         DefraStoreConfig `yaml:"store"`
+	Network       NetworkConfig    `yaml:"network"`
+}
+
+// NetworkConfig controls which local address is detected as this machine's LAN address. Detection only enumerates
+// network interfaces, so it works without outbound connectivity.
+type NetworkConfig struct {
+	// Interface restricts detection to the named interface, e.g. "eth0"
+	Interface string `yaml:"interface"`
+	// CIDR restricts detection to addresses within the given network, e.g. "192.168.0.0/16"
+	CIDR string `yaml:"cidr"`
+	// IPVersion restricts detection to IPv4 (4) or IPv6 (6) addresses; 0 allows either, preferring IPv4
+	IPVersion int `yaml:"ip_version"`
+	// LoopbackOnly makes detection return a loopback address, unreachable from other machines
+	LoopbackOnly bool `yaml:"loopback_only"`
+	// RewriteLoopback opts in to replacing localhost/127.0.0.1/::1 in Url and P2P.ListenAddr with the detected LAN
+	// address before binding. Off by default, so configured addresses are used exactly as given.
+	RewriteLoopback bool `yaml:"rewrite_loopback"`
 }
 
 type DefraP2PConfig struct {
 	BootstrapPeers []string `yaml:"bootstrap_peers"`
-	ListenAddr     string   `yaml:"listen_addr"`
+	// BootstrapAttempts is how many times each bootstrap peer is tried, with backoff, during startup. 0 uses the
+	// SDK's default.
+	BootstrapAttempts int `yaml:"bootstrap_attempts"`
+	// MinConnectedPeers is how many bootstrap or peer book peers must be connected for startup to succeed. With the
+	// default of 0, unreachable peers never prevent the node from starting.
+	MinConnectedPeers int `yaml:"min_connected_peers"`
+	// PeerBookReconnect is how many of the peers remembered from earlier runs (in peers.json under the store path)
+	// are reconnected on startup, healthiest first. 0 uses the SDK's default; a negative value disables it.
+	PeerBookReconnect int `yaml:"peer_book_reconnect"`
+	// ReconnectInterval is how often bootstrap peers are re-dialled after startup, so that peers which were
+	// unreachable or have dropped are reconnected, e.g. "30s". 0 uses the SDK's default; a negative value disables it.
+	ReconnectInterval time.Duration `yaml:"reconnect_interval"`
+	// ListenAddr is the multiaddr the P2P host binds to, used as-is
+	ListenAddr string `yaml:"listen_addr"`
+	// AnnounceAddrs are the multiaddrs (without /p2p/) other peers should dial to reach this node, if they differ
+	// from the addresses it listens on (e.g. behind NAT)
+	AnnounceAddrs []string `yaml:"announce_addrs"`
+	// MDNS finds and connects to other nodes on the local network, without configuring bootstrap peers
+	MDNS MDNSConfig `yaml:"mdns"`
+	// PeerFilter restricts which peers the node talks to, whichever side dials
+	PeerFilter PeerFilterConfig `yaml:"peer_filter"`
+}
+
+// PeerFilterConfig restricts which peers the node connects to and serves blocks to, for connections in either
+// direction. Denials take precedence over the allowlist.
+type PeerFilterConfig struct {
+	// AllowedPeers, if set, are the only peer IDs the node talks to
+	AllowedPeers []string `yaml:"allowed_peers"`
+	// DeniedPeers are peer IDs the node never talks to
+	DeniedPeers []string `yaml:"denied_peers"`
+	// DeniedCIDRs are networks, e.g. "10.0.0.0/8", whose addresses the node never dials or accepts connections from
+	DeniedCIDRs []string `yaml:"denied_cidrs"`
+	// MaxPeers caps how many peers the node is connected to; 0 means no limit
+	MaxPeers int `yaml:"max_peers"`
+}
+
+// MDNSConfig controls local network peer discovery over mDNS. Nodes advertise themselves under a service tag and only
+// connect to nodes advertising the same one, so separate deployments on one network can stay apart.
+type MDNSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// ServiceTag is advertised as the _<tag>._udp mDNS service: up to 15 letters, digits or hyphens. Empty uses the
+	// SDK's default.
+	ServiceTag string `yaml:"service_tag"`
 }
 
 type DefraStoreConfig struct {
 	Path string `yaml:"path"`
+	// InMemory keeps the store, keyring and node identity in memory instead of under Path. Nothing touches the
+	// filesystem, and everything - including the node's identity - is lost when the node is closed.
+	InMemory bool `yaml:"in_memory"`
 }
 
 type ShinzoConfig struct {
 	MinimumAttestations string `yaml:"minimum_attestations"`
+	// TrustedSigners is an allowlist of indexer public keys whose signatures count as attestations
+	TrustedSigners []string `yaml:"trusted_signers"`
+	// TrustedSignersFile is an optional signed file of additional trusted indexer public keys
+	TrustedSignersFile string `yaml:"trusted_signers_file"`
+	// TrustedSignersAuthority is the public key expected to have signed the TrustedSignersFile
+	TrustedSignersAuthority string `yaml:"trusted_signers_authority"`
+}
+
+// GetMinimumAttestations parses the configured minimum attestation threshold.
+// An empty value is treated as 0 (no attestation filtering).
+func (c ShinzoConfig) GetMinimumAttestations() (int, error) {
+	value := strings.TrimSpace(c.MinimumAttestations)
+	if value == "" {
+		return 0, nil
+	}
+
+	minimum, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid minimum_attestations %q: %w", c.MinimumAttestations, err)
+	}
+	if minimum < 0 {
+		return 0, fmt.Errorf("minimum_attestations cannot be negative, given: %d", minimum)
+	}
+	return minimum, nil
 }
 
 type LoggerConfig struct {
@@ -65,5 +169,9 @@ func LoadConfig(path string) (*Config, error) {
 		cfg.DefraDB.Url = url
 	}
 
+	if mode := os.Getenv("DEFRA_MODE"); mode != "" {
+		cfg.DefraDB.Mode = mode
+	}
+
 	return &cfg, nil
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig_ValidYAML(t *testing.T) {
@@ -14,13 +15,29 @@ func TestLoadConfig_ValidYAML(t *testing.T) {
 	configContent := `
 defradb:
   url: "http://localhost:9181"
+  announce_url: "https://defra.example.com"
   keyring_secret: "test_secret"
   p2p:
     enabled: true
     bootstrap_peers: ["peer1", "peer2"]
     listen_addr: "/ip4/0.0.0.0/tcp/9171"
+    announce_addrs: ["/dns4/defra.example.com/tcp/9171"]
+    bootstrap_attempts: 5
+    min_connected_peers: 1
+    peer_book_reconnect: -1
+    reconnect_interval: "45s"
+    mdns:
+      enabled: true
+      service_tag: "office"
+    peer_filter:
+      allowed_peers: ["peerA"]
+      denied_peers: ["peerB"]
+      denied_cidrs: ["10.0.0.0/8"]
+      max_peers: 20
   store:
     path: "/tmp/defra"
+  network:
+    rewrite_loopback: true
 `
 
 	err := os.WriteFile(configPath, []byte(configContent), 0644)
@@ -46,6 +63,37 @@ defradb:
 	if len(cfg.DefraDB.P2P.BootstrapPeers) != 2 {
 		t.Errorf("Expected 2 bootstrap peers, got %d", len(cfg.DefraDB.P2P.BootstrapPeers))
 	}
+	if cfg.DefraDB.P2P.BootstrapAttempts != 5 {
+		t.Errorf("Expected bootstrap_attempts 5, got %d", cfg.DefraDB.P2P.BootstrapAttempts)
+	}
+	if cfg.DefraDB.P2P.MinConnectedPeers != 1 {
+		t.Errorf("Expected min_connected_peers 1, got %d", cfg.DefraDB.P2P.MinConnectedPeers)
+	}
+	if cfg.DefraDB.P2P.PeerBookReconnect != -1 {
+		t.Errorf("Expected peer_book_reconnect -1, got %d", cfg.DefraDB.P2P.PeerBookReconnect)
+	}
+	if cfg.DefraDB.P2P.ReconnectInterval != 45*time.Second {
+		t.Errorf("Expected reconnect_interval 45s, got %v", cfg.DefraDB.P2P.ReconnectInterval)
+	}
+	if !cfg.DefraDB.P2P.MDNS.Enabled || cfg.DefraDB.P2P.MDNS.ServiceTag != "office" {
+		t.Errorf("Expected mdns enabled with service_tag 'office', got %+v", cfg.DefraDB.P2P.MDNS)
+	}
+	peerFilter := cfg.DefraDB.P2P.PeerFilter
+	if len(peerFilter.AllowedPeers) != 1 || len(peerFilter.DeniedPeers) != 1 || len(peerFilter.DeniedCIDRs) != 1 ||
+		peerFilter.MaxPeers != 20 {
+		t.Errorf("Expected peer filter with one allowed peer, one denied peer, one denied CIDR and max 20 peers, got %+v", peerFilter)
+	}
+
+	// Test announce addresses
+	if cfg.DefraDB.AnnounceUrl != "https://defra.example.com" {
+		t.Errorf("Expected announce_url 'https://defra.example.com', got '%s'", cfg.DefraDB.AnnounceUrl)
+	}
+	if len(cfg.DefraDB.P2P.AnnounceAddrs) != 1 || cfg.DefraDB.P2P.AnnounceAddrs[0] != "/dns4/defra.example.com/tcp/9171" {
+		t.Errorf("Expected announce_addrs ['/dns4/defra.example.com/tcp/9171'], got %v", cfg.DefraDB.P2P.AnnounceAddrs)
+	}
+	if !cfg.DefraDB.Network.RewriteLoopback {
+		t.Errorf("Expected rewrite_loopback to be true")
+	}
 }
 
 func TestLoadConfig_EnvironmentOverrides(t *testing.T) {
@@ -67,11 +115,13 @@ defradb:
 	// Set environment variables
 	os.Setenv("DEFRA_KEYRING_SECRET", "env_secret")
 	os.Setenv("DEFRA_URL", "someUrl")
+	os.Setenv("DEFRA_MODE", DefraModeRemote)
 
 	// Clean up environment variables after test
 	defer func() {
 		os.Unsetenv("DEFRA_KEYRING_SECRET")
 		os.Unsetenv("DEFRA_URL")
+		os.Unsetenv("DEFRA_MODE")
 	}()
 
 	cfg, err := LoadConfig(configPath)
@@ -86,6 +136,9 @@ defradb:
 	if cfg.DefraDB.Url != "someUrl" {
 		t.Errorf("Expected host 'someUrl', got '%s'", cfg.DefraDB.Url)
 	}
+	if cfg.DefraDB.Mode != DefraModeRemote {
+		t.Errorf("Expected mode '%s', got '%s'", DefraModeRemote, cfg.DefraDB.Mode)
+	}
 }
 
 func TestLoadConfig_InvalidPath(t *testing.T) {
@@ -115,3 +168,36 @@ defradb:
 		t.Error("Expected error for invalid YAML, got nil")
 	}
 }
+
+func TestShinzoConfig_GetMinimumAttestations(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    int
+		expectError bool
+	}{
+		{name: "empty defaults to zero", value: "", expected: 0},
+		{name: "valid value", value: "3", expected: 3},
+		{name: "surrounding whitespace", value: " 2 ", expected: 2},
+		{name: "not a number", value: "three", expectError: true},
+		{name: "negative", value: "-1", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minimum, err := ShinzoConfig{MinimumAttestations: tt.value}.GetMinimumAttestations()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q, got nil", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if minimum != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, minimum)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package defra
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/shinzonetwork/app-sdk/pkg/networking"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// apiBindAddress returns the host:port the HTTP API listens on for the configured url, which may or may not include a
+// scheme (e.g. "http://localhost:9181" or "localhost:9181"). An empty url binds to defaultAPIAddress, on loopback.
+func apiBindAddress(url string) string {
+	if url == "" {
+		return defaultAPIAddress
+	}
+	if _, afterScheme, found := strings.Cut(url, "://"); found {
+		url = afterScheme
+	}
+	hostPort, _, _ := strings.Cut(url, "/")
+	return hostPort
+}
+
+// rewriteLoopback replaces a loopback host in the API bind address and P2P listen address with the detected LAN
+// address. Only used when the operator opts in with network.rewrite_loopback.
+func rewriteLoopback(cfg *config.Config, apiAddress string, listenAddress string) (string, string, error) {
+	ipAddress, err := lanIP(cfg)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get LAN IP address: %w", err)
+	}
+
+	if host, port, err := net.SplitHostPort(apiAddress); err == nil && isLoopbackHost(host) {
+		apiAddress = net.JoinHostPort(ipAddress, port)
+	}
+
+	ipPrefix := fmt.Sprintf("/%s/%s", ipProtocol(ipAddress), ipAddress)
+	for _, loopback := range []string{"/ip4/127.0.0.1/", "/ip6/::1/", "/dns/localhost/", "/dns4/localhost/", "/dns6/localhost/"} {
+		if strings.HasPrefix(listenAddress, loopback) {
+			listenAddress = ipPrefix + listenAddress[len(loopback)-1:]
+			break
+		}
+	}
+	return apiAddress, listenAddress, nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// lanIP detects this machine's LAN address, following the config's network preferences
+func lanIP(cfg *config.Config) (string, error) {
+	return networking.GetLANIPWithPreferences(networking.Preferences{
+		Interface:    cfg.DefraDB.Network.Interface,
+		CIDR:         cfg.DefraDB.Network.CIDR,
+		IPVersion:    cfg.DefraDB.Network.IPVersion,
+		LoopbackOnly: cfg.DefraDB.Network.LoopbackOnly,
+	})
+}
+
+// ipProtocol returns the multiaddr protocol for an IP address: ip4 or ip6
+func ipProtocol(ipAddress string) string {
+	if networking.IsIPv6(ipAddress) {
+		return "ip6"
+	}
+	return "ip4"
+}
+
+// AnnouncedPeers returns the addresses, in bootstrap peer form (<multiaddr>/p2p/<peer ID>), that other nodes should
+// use to connect to defraNode. These are cfg.DefraDB.P2P.AnnounceAddrs if configured, otherwise the addresses the
+// node is listening on.
+func AnnouncedPeers(defraNode *node.Node, cfg *config.Config) ([]string, error) {
+	listening, err := defraNode.DB.PeerInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peer info: %w", err)
+	}
+	if cfg == nil || len(cfg.DefraDB.P2P.AnnounceAddrs) == 0 {
+		return listening, nil
+	}
+	if len(listening) == 0 {
+		return nil, fmt.Errorf("node has no peer ID to announce")
+	}
+
+	// Every listening address ends with the node's own /p2p/<peer ID>
+	index := strings.LastIndex(listening[0], "/p2p/")
+	if index < 0 {
+		return nil, fmt.Errorf("node's peer info %s has no peer ID", listening[0])
+	}
+	peerPart := listening[0][index:]
+
+	announced := make([]string, 0, len(cfg.DefraDB.P2P.AnnounceAddrs))
+	for _, address := range cfg.DefraDB.P2P.AnnounceAddrs {
+		announced = append(announced, strings.TrimSuffix(address, "/")+peerPart)
+	}
+	return announced, nil
+}
+
+// AnnouncedAPIURL returns the URL other machines should use to reach defraNode's HTTP API: cfg.DefraDB.AnnounceUrl
+// if configured, otherwise the URL the API is listening on.
+func AnnouncedAPIURL(defraNode *node.Node, cfg *config.Config) string {
+	if cfg != nil && cfg.DefraDB.AnnounceUrl != "" {
+		return cfg.DefraDB.AnnounceUrl
+	}
+	return defraNode.APIURL
+}
@@ -0,0 +1,69 @@
+package defra
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiBindAddress(t *testing.T) {
+	require.Equal(t, defaultAPIAddress, apiBindAddress(""))
+	require.Equal(t, "localhost:9181", apiBindAddress("http://localhost:9181"))
+	require.Equal(t, "127.0.0.1:0", apiBindAddress("127.0.0.1:0"))
+	require.Equal(t, "0.0.0.0:9181", apiBindAddress("https://0.0.0.0:9181/api/v0"))
+	require.Equal(t, "[::1]:9181", apiBindAddress("http://[::1]:9181"))
+}
+
+func TestRewriteLoopback(t *testing.T) {
+	cfg := &config.Config{}
+	ipAddress, err := lanIP(cfg)
+	require.NoError(t, err)
+
+	apiAddress, listenAddress, err := rewriteLoopback(cfg, "localhost:9181", "/ip4/127.0.0.1/tcp/9171")
+	require.NoError(t, err)
+	require.Equal(t, net.JoinHostPort(ipAddress, "9181"), apiAddress)
+	require.Equal(t, "/"+ipProtocol(ipAddress)+"/"+ipAddress+"/tcp/9171", listenAddress)
+
+	// Addresses that aren't loopback are left alone
+	apiAddress, listenAddress, err = rewriteLoopback(cfg, "0.0.0.0:9181", "/ip4/0.0.0.0/tcp/9171")
+	require.NoError(t, err)
+	require.Equal(t, "0.0.0.0:9181", apiAddress)
+	require.Equal(t, "/ip4/0.0.0.0/tcp/9171", listenAddress)
+}
+
+func TestStartBindsConfiguredAddresses(t *testing.T) {
+	testConfig := *DefaultConfig
+	testConfig.DefraDB.Url = "http://127.0.0.1:0"
+	testConfig.DefraDB.P2P.ListenAddr = "/ip4/127.0.0.1/tcp/0"
+	testConfig.DefraDB.Store.Path = t.TempDir()
+	testConfig.DefraDB.KeyringSecret = "testSecret"
+
+	myNode, err := Start(t.Context(), &testConfig, &MockSchemaApplierThatSucceeds{})
+	require.NoError(t, err)
+	defer myNode.Close(t.Context())
+
+	// Loopback is not rewritten to the LAN address unless network.rewrite_loopback is set
+	require.Contains(t, myNode.APIURL, "127.0.0.1")
+	peerInfo, err := myNode.DB.PeerInfo()
+	require.NoError(t, err)
+	require.NotEmpty(t, peerInfo)
+	for _, address := range peerInfo {
+		require.True(t, strings.HasPrefix(address, "/ip4/127.0.0.1/"), address)
+	}
+
+	announced, err := AnnouncedPeers(myNode, &testConfig)
+	require.NoError(t, err)
+	require.Equal(t, peerInfo, announced)
+	require.Equal(t, myNode.APIURL, AnnouncedAPIURL(myNode, &testConfig))
+
+	testConfig.DefraDB.P2P.AnnounceAddrs = []string{"/dns4/indexer.example.com/tcp/9171"}
+	testConfig.DefraDB.AnnounceUrl = "https://indexer.example.com"
+	announced, err = AnnouncedPeers(myNode, &testConfig)
+	require.NoError(t, err)
+	peerID := peerInfo[0][strings.LastIndex(peerInfo[0], "/p2p/"):]
+	require.Equal(t, []string{"/dns4/indexer.example.com/tcp/9171" + peerID}, announced)
+	require.Equal(t, "https://indexer.example.com", AnnouncedAPIURL(myNode, &testConfig))
+}
@@ -0,0 +1,288 @@
+package defra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	sdkerrors "github.com/shinzonetwork/app-sdk/pkg/errors"
+	"github.com/shinzonetwork/app-sdk/pkg/logger"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+const (
+	// DefaultBootstrapAttempts is how many times each bootstrap peer is tried during startup, unless configured
+	DefaultBootstrapAttempts = 3
+	// DefaultReconnectInterval is how often bootstrap peers are re-dialled after startup, unless configured
+	DefaultReconnectInterval = 30 * time.Second
+	// peerDialTimeout bounds a single connection attempt, so that one unresponsive peer can't stall the others
+	peerDialTimeout = 10 * time.Second
+)
+
+// PeerState is the connection state of a bootstrap peer
+type PeerState string
+
+const (
+	// PeerPending peers have not been tried yet
+	PeerPending PeerState = "pending"
+	// PeerConnected peers were connected by the most recent attempt
+	PeerConnected PeerState = "connected"
+	// PeerFailed peers could not be connected by the most recent attempt; they are retried in the background
+	PeerFailed PeerState = "failed"
+	// PeerBlocked peers were refused by the node's PeerFilter; they are checked again every reconnect interval, in
+	// case its rules have changed
+	PeerBlocked PeerState = "blocked"
+)
+
+// PeerSource is where a peer dialled on startup came from
+type PeerSource string
+
+const (
+	// PeerSourceBootstrap peers are configured in DefraP2PConfig.BootstrapPeers
+	PeerSourceBootstrap PeerSource = "bootstrap"
+	// PeerSourcePeerBook peers were connected in an earlier run and remembered in the node's PeerBook
+	PeerSourcePeerBook PeerSource = "peer_book"
+)
+
+// PeerStatus reports how connecting to a bootstrap peer is going
+type PeerStatus struct {
+	// Address is the bootstrap peer as configured, e.g. /ip4/1.2.3.4/tcp/9171/p2p/<peer ID>, or the first of a peer
+	// book entry's addresses
+	Address string
+	Source  PeerSource
+	State   PeerState
+	// Attempts counts every connection attempt, successful or not
+	Attempts int
+	// ConsecutiveFailures counts the failed attempts since the peer was last connected; it drives the retry backoff
+	ConsecutiveFailures int
+	// LastError is the error from the most recent failed attempt, cleared once the peer connects
+	LastError   error
+	LastAttempt time.Time
+	// NextAttempt is when the peer will next be dialled, if reconnecting in the background
+	NextAttempt time.Time
+}
+
+// bootstrapper connects a node to its bootstrap peers one by one, retrying each with backoff, and keeps re-dialling
+// them in the background for as long as the node runs. Connections are recorded in the node's peer book, and peers
+// refused by the peer filter aren't dialled.
+type bootstrapper struct {
+	defraNode *node.Node
+	book      *PeerBook
+	filter    *PeerFilter
+
+	mu    sync.Mutex
+	peers []*bootstrapPeer
+}
+
+type bootstrapPeer struct {
+	addresses []string
+	status    PeerStatus
+}
+
+// newBootstrapper dials the configured bootstrap peers, plus up to fromBook of the healthiest peers in book that
+// aren't among them. filter may be nil to dial every peer.
+func newBootstrapper(defraNode *node.Node, peers []string, book *PeerBook, fromBook int, filter *PeerFilter) *bootstrapper {
+	b := &bootstrapper{defraNode: defraNode, book: book, filter: filter}
+	seen := map[string]struct{}{}
+	for _, address := range peers {
+		if _, ok := seen[address]; ok {
+			continue
+		}
+		seen[address] = struct{}{}
+		if id, _, err := parsePeerAddresses([]string{address}); err == nil {
+			seen[id.String()] = struct{}{}
+		}
+		b.peers = append(b.peers, &bootstrapPeer{
+			addresses: []string{address},
+			status:    PeerStatus{Address: address, Source: PeerSourceBootstrap, State: PeerPending},
+		})
+	}
+
+	if book == nil {
+		return b
+	}
+	for _, entry := range book.List() {
+		if fromBook <= 0 {
+			break
+		}
+		if _, ok := seen[entry.ID]; ok || len(entry.Addresses) == 0 {
+			continue
+		}
+		b.peers = append(b.peers, &bootstrapPeer{
+			addresses: entry.Addresses,
+			status:    PeerStatus{Address: entry.Addresses[0], Source: PeerSourcePeerBook, State: PeerPending},
+		})
+		fromBook--
+	}
+	return b
+}
+
+// connect tries every peer concurrently, up to attempts times each with errors.GetRetryDelay between tries, and returns
+// an error if fewer than minConnected peers ended up connected
+func (b *bootstrapper) connect(ctx context.Context, attempts int, minConnected int) error {
+	if attempts <= 0 {
+		attempts = DefaultBootstrapAttempts
+	}
+	var wg sync.WaitGroup
+	for _, p := range b.peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for attempt := 0; attempt < attempts; attempt++ {
+				err := b.dial(ctx, p)
+				if err == nil || attempt == attempts-1 || errors.Is(err, ErrPeerFiltered) {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(sdkerrors.GetRetryDelay(err, attempt)):
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	connected := 0
+	for _, status := range b.Status() {
+		if status.State == PeerConnected {
+			connected++
+		} else {
+			logger.Sugar.Warnf("Failed to connect to %s peer %s after %d attempts: %v", status.Source, status.Address, status.Attempts, status.LastError)
+		}
+	}
+	if connected < minConnected {
+		return fmt.Errorf("connected to %d of %d peers, at least %d required", connected, len(b.peers), minConnected)
+	}
+	return nil
+}
+
+// dial makes a single connection attempt to a peer and records the outcome in its status and the peer book
+func (b *bootstrapper) dial(ctx context.Context, p *bootstrapPeer) error {
+	addresses := p.addresses
+	if b.filter != nil {
+		allowed, err := b.filter.allowedAddresses(p.addresses)
+		if err != nil {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			p.status.State = PeerBlocked
+			p.status.LastError = err
+			p.status.LastAttempt = time.Now()
+			return err
+		}
+		addresses = allowed
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, peerDialTimeout)
+	defer cancel()
+	err := b.defraNode.DB.Connect(dialCtx, addresses)
+	if err != nil {
+		err = sdkerrors.NewPeerConnectionFailed("defra", "connect", p.status.Address, err)
+	}
+	if b.book != nil {
+		record := b.book.recordConnected
+		if err != nil {
+			record = b.book.recordFailure
+		}
+		if bookErr := record(p.addresses); bookErr != nil {
+			logger.Sugar.Debugf("Failed to update peer book for %s: %v", p.status.Address, bookErr)
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := &p.status
+	status.Attempts++
+	status.LastAttempt = time.Now()
+	if err != nil {
+		status.State = PeerFailed
+		status.ConsecutiveFailures++
+		status.LastError = err
+	} else {
+		status.State = PeerConnected
+		status.ConsecutiveFailures = 0
+		status.LastError = nil
+	}
+	return err
+}
+
+// reconnect re-dials every peer until the node is closed: connected peers every interval, so that a dropped
+// connection is re-established, and failed peers sooner, backing off with errors.GetRetryDelay
+func (b *bootstrapper) reconnect(interval time.Duration) {
+	if len(b.peers) == 0 {
+		return
+	}
+	ctx, err := untilClosed(b.defraNode)
+	if err != nil {
+		logger.Sugar.Warnf("Not reconnecting to bootstrap peers: %v", err)
+		return
+	}
+
+	for {
+		b.scheduleNextAttempts(interval)
+		next := time.Time{}
+		for _, status := range b.Status() {
+			if next.IsZero() || status.NextAttempt.Before(next) {
+				next = status.NextAttempt
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		var wg sync.WaitGroup
+		for _, p := range b.peers {
+			b.mu.Lock()
+			due := !p.status.NextAttempt.After(time.Now())
+			b.mu.Unlock()
+			if !due {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := b.dial(ctx, p); err != nil {
+					logger.Sugar.Debugf("Failed to reconnect to %s peer %s: %v", p.status.Source, p.status.Address, err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+// scheduleNextAttempts sets when each peer is next dialled, for any peer that isn't already scheduled. Blocked peers
+// wait a full interval, like connected ones.
+func (b *bootstrapper) scheduleNextAttempts(interval time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	for _, p := range b.peers {
+		status := &p.status
+		if status.NextAttempt.After(now) {
+			continue
+		}
+		if status.State == PeerFailed {
+			status.NextAttempt = now.Add(sdkerrors.GetRetryDelay(status.LastError, status.ConsecutiveFailures))
+		} else {
+			status.NextAttempt = now.Add(interval)
+		}
+	}
+}
+
+// Status returns a snapshot of every peer's status: bootstrap peers in configured order, then peer book entries
+func (b *bootstrapper) Status() []PeerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	statuses := make([]PeerStatus, len(b.peers))
+	for i, p := range b.peers {
+		statuses[i] = p.status
+	}
+	return statuses
+}
@@ -0,0 +1,95 @@
+package defra
+
+import (
+	"testing"
+	"time"
+
+	sdkerrors "github.com/shinzonetwork/app-sdk/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// deadPeer has a valid peer ID but nothing listening on its address
+const deadPeer = "/ip4/127.0.0.1/tcp/1/p2p/12D3KooWLttXvtbokAphdVWL6hx7VEviDnHYwQs5SmAw1Y1yfcZT"
+
+func TestStartWithUnreachableBootstrapPeer(t *testing.T) {
+	schema := NewSchemaApplierFromProvidedSchema(`type User { name: String }`)
+	live, err := Start(t.Context(), syncTestConfig(), schema, "User")
+	require.NoError(t, err)
+	defer live.Close(t.Context())
+	livePeerInfo, err := live.DB.PeerInfo()
+	require.NoError(t, err)
+
+	cfg := syncTestConfig()
+	cfg.DefraDB.P2P.BootstrapPeers = []string{deadPeer, livePeerInfo[0]}
+	cfg.DefraDB.P2P.BootstrapAttempts = 2
+	cfg.DefraDB.P2P.MinConnectedPeers = 1
+	defraNode, err := StartNode(t.Context(), cfg, schema, "User")
+	require.NoError(t, err, "one dead bootstrap peer should not stop the node from starting")
+	defer defraNode.Close(t.Context())
+
+	statuses, err := defraNode.BootstrapPeerStatus()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	require.Equal(t, deadPeer, statuses[0].Address)
+	require.Equal(t, PeerFailed, statuses[0].State)
+	require.Equal(t, 2, statuses[0].Attempts)
+	require.Equal(t, 2, statuses[0].ConsecutiveFailures)
+	require.True(t, sdkerrors.IsNetworkError(statuses[0].LastError))
+
+	require.Equal(t, PeerConnected, statuses[1].State)
+	require.Equal(t, 1, statuses[1].Attempts)
+	require.NoError(t, statuses[1].LastError)
+}
+
+func TestStartFailsBelowMinConnectedPeers(t *testing.T) {
+	cfg := syncTestConfig()
+	cfg.DefraDB.P2P.BootstrapPeers = []string{deadPeer}
+	cfg.DefraDB.P2P.BootstrapAttempts = 1
+	cfg.DefraDB.P2P.MinConnectedPeers = 1
+	_, err := Start(t.Context(), cfg, NewSchemaApplierFromProvidedSchema(`type User { name: String }`), "User")
+	require.Error(t, err)
+}
+
+func TestBootstrapRetriesFailedPeersInBackground(t *testing.T) {
+	cfg := syncTestConfig()
+	cfg.DefraDB.P2P.BootstrapPeers = []string{deadPeer}
+	cfg.DefraDB.P2P.BootstrapAttempts = 1
+	cfg.DefraDB.P2P.ReconnectInterval = time.Second
+	defraNode, err := StartNode(t.Context(), cfg, NewSchemaApplierFromProvidedSchema(`type User { name: String }`), "User")
+	require.NoError(t, err)
+	defer defraNode.Close(t.Context())
+	statuses, err := defraNode.BootstrapPeerStatus()
+	require.NoError(t, err)
+	require.Equal(t, 1, statuses[0].Attempts)
+
+	// Retries back off with errors.GetRetryDelay, so the first comes a couple of seconds later
+	require.Eventually(t, func() bool {
+		statuses, err := defraNode.BootstrapPeerStatus()
+		return err == nil && statuses[0].Attempts > 1
+	}, 10*time.Second, 100*time.Millisecond, "the failed peer should be retried in the background")
+}
+
+func TestBootstrapReconnectCanBeDisabled(t *testing.T) {
+	cfg := syncTestConfig()
+	cfg.DefraDB.P2P.BootstrapPeers = []string{deadPeer}
+	cfg.DefraDB.P2P.BootstrapAttempts = 1
+	cfg.DefraDB.P2P.ReconnectInterval = -1
+	defraNode, err := StartNode(t.Context(), cfg, NewSchemaApplierFromProvidedSchema(`type User { name: String }`), "User")
+	require.NoError(t, err)
+	defer defraNode.Close(t.Context())
+
+	time.Sleep(2 * time.Second)
+	statuses, err := defraNode.BootstrapPeerStatus()
+	require.NoError(t, err)
+	require.Equal(t, 1, statuses[0].Attempts)
+	require.True(t, statuses[0].NextAttempt.IsZero())
+}
+
+func TestBootstrapPeerStatusOfUnmanagedNode(t *testing.T) {
+	defraNode, err := Start(t.Context(), syncTestConfig(), NewSchemaApplierFromProvidedSchema(`type User { name: String }`), "User")
+	require.NoError(t, err)
+	defer defraNode.Close(t.Context())
+
+	_, err = (&Node{Node: defraNode}).BootstrapPeerStatus()
+	require.ErrorIs(t, err, ErrUnmanagedNode)
+}
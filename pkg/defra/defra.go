@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,7 +14,6 @@ import (
 	libp2pcrypto "github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/shinzonetwork/app-sdk/pkg/config"
 	"github.com/shinzonetwork/app-sdk/pkg/logger"
-	"github.com/shinzonetwork/app-sdk/pkg/networking"
 	"github.com/sourcenetwork/defradb/acp/identity"
 	"github.com/sourcenetwork/defradb/crypto"
 	"github.com/sourcenetwork/defradb/http"
@@ -40,7 +40,10 @@ var DefaultConfig *config.Config = &config.Config{
 }
 
 var requiredPeers []string = []string{} // Here, we can add some "big peers" to give nodes a starting place when building their peer network
-const defaultListenAddress string = "/ip4/127.0.0.1/tcp/9171"
+
+// P2P is meant to be reachable by other nodes, so by default it binds every interface; the HTTP API only binds loopback
+const defaultListenAddress string = "/ip4/0.0.0.0/tcp/9171"
+const defaultAPIAddress string = "127.0.0.1:9181"
 const nodeIdentityKeyName string = "node-identity-key"
 
 // Key Management Implementation Notes:
@@ -89,14 +92,27 @@ func openKeyring(cfg *config.Config) (keyring.Keyring, error) {
 	return keyring.OpenFileKeyring(keyringPath, secret)
 }
 
+// openNodeKeyring opens the keyring holding the node's identity: an in-memory keyring for in-memory nodes, otherwise
+// the file keyring from the config
+func openNodeKeyring(cfg *config.Config) (keyring.Keyring, error) {
+	if cfg != nil && cfg.DefraDB.Store.InMemory {
+		return newMemoryKeyring(), nil
+	}
+	return openKeyring(cfg)
+}
+
 // getOrCreateNodeIdentity retrieves an existing node identity from keyring or creates a new one
 func getOrCreateNodeIdentity(cfg *config.Config) (identity.Identity, error) {
 	// Open keyring (required, no fallback)
-	kr, err := openKeyring(cfg)
+	kr, err := openNodeKeyring(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open keyring: %w", err)
 	}
+	return getOrCreateNodeIdentityInKeyring(kr)
+}
 
+// getOrCreateNodeIdentityInKeyring retrieves an existing node identity from the given keyring or creates a new one
+func getOrCreateNodeIdentityInKeyring(kr keyring.Keyring) (identity.Identity, error) {
 	// Try to load existing identity from keyring
 	identityBytes, err := kr.Get(nodeIdentityKeyName)
 	if err != nil {
@@ -226,11 +242,42 @@ func createLibP2PKeyFromIdentity(nodeIdentity identity.Identity) (libp2pcrypto.P
 }
 
 func StartDefraInstance(cfg *config.Config, schemaApplier SchemaApplier, collectionsOfInterest ...string) (*node.Node, error) {
-	ctx := context.Background()
+	return Start(context.Background(), cfg, schemaApplier, collectionsOfInterest...)
+}
 
+// Start starts a defra instance like StartNode, returning just the DefraDB node. Bootstrap peers are still retried in
+// the background, but their status is only available from StartNode's Node.
+func Start(ctx context.Context, cfg *config.Config, schemaApplier SchemaApplier, collectionsOfInterest ...string) (*node.Node, error) {
+	defraNode, err := StartNode(ctx, cfg, schemaApplier, collectionsOfInterest...)
+	if err != nil {
+		return nil, err
+	}
+	return defraNode.Node, nil
+}
+
+// StartNode starts a defra instance, applies the schema and subscribes to the collections of interest.
+// If cfg.DefraDB.Mode is config.DefraModeRemote, no node is started; instead StartNode connects to the DefraDB serving
+// its HTTP API at cfg.DefraDB.Url (see ConnectToRemote).
+// Startup is abandoned as soon as ctx is cancelled or its deadline passes - use context.WithTimeout to bound how long
+// connecting to bootstrap peers may take. If any step fails, the node is closed before StartNode returns.
+func StartNode(ctx context.Context, cfg *config.Config, schemaApplier SchemaApplier, collectionsOfInterest ...string) (*Node, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("config cannot be nil")
 	}
+	if schemaApplier == nil {
+		return nil, fmt.Errorf("schema applier cannot be nil")
+	}
+	switch cfg.DefraDB.Mode {
+	case config.DefraModeEmbedded, "":
+	case config.DefraModeRemote:
+		remoteNode, err := startRemote(ctx, cfg, schemaApplier, collectionsOfInterest...)
+		if err != nil {
+			return nil, err
+		}
+		return &Node{Node: remoteNode}, nil
+	default:
+		return nil, fmt.Errorf("unknown defradb mode %q", cfg.DefraDB.Mode)
+	}
 	cfg.DefraDB.P2P.BootstrapPeers = append(cfg.DefraDB.P2P.BootstrapPeers, requiredPeers...)
 	if len(cfg.DefraDB.P2P.ListenAddr) == 0 {
 		cfg.DefraDB.P2P.ListenAddr = defaultListenAddress
@@ -238,8 +285,12 @@ func StartDefraInstance(cfg *config.Config, schemaApplier SchemaApplier, collect
 
 	logger.Init(cfg.Logger.Development)
 
-	// Use persistent identity from keyring (required, no fallback)
-	nodeIdentity, err := getOrCreateNodeIdentity(cfg)
+	// Use persistent identity from keyring (required, no fallback), or a new one held in memory for in-memory nodes
+	kr, err := openNodeKeyring(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error getting or creating identity: failed to open keyring: %w", err)
+	}
+	nodeIdentity, err := getOrCreateNodeIdentityInKeyring(kr)
 	if err != nil {
 		return nil, fmt.Errorf("error getting or creating identity: %w", err)
 	}
@@ -256,32 +307,22 @@ func StartDefraInstance(cfg *config.Config, schemaApplier SchemaApplier, collect
 		return nil, fmt.Errorf("error getting LibP2P private key bytes: %v", err)
 	}
 
-	// Get real IP address to replace loopback addresses
-	ipAddress, err := networking.GetLANIP()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get LAN IP address: %v", err)
-	}
-
-	// Replace loopback addresses in URL with real IP
-	defraUrl := cfg.DefraDB.Url
-	defraUrl = strings.Replace(defraUrl, "http://localhost", ipAddress, 1)
-	defraUrl = strings.Replace(defraUrl, "http://127.0.0.1", ipAddress, 1)
-	defraUrl = strings.Replace(defraUrl, "localhost", ipAddress, 1)
-	defraUrl = strings.Replace(defraUrl, "127.0.0.1", ipAddress, 1)
-
-	// Replace loopback addresses in listen address with real IP
+	// Bind exactly where configured, unless the operator opted in to having loopback replaced with the LAN address
+	apiAddress := apiBindAddress(cfg.DefraDB.Url)
 	listenAddress := cfg.DefraDB.P2P.ListenAddr
-	if len(listenAddress) > 0 {
-		listenAddress = strings.Replace(listenAddress, "127.0.0.1", ipAddress, 1)
-		listenAddress = strings.Replace(listenAddress, "localhost", ipAddress, 1)
+	if cfg.DefraDB.Network.RewriteLoopback {
+		apiAddress, listenAddress, err = rewriteLoopback(cfg, apiAddress, listenAddress)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Create defra node options
 	options := []node.Option{
 		node.WithDisableAPI(false),
 		node.WithDisableP2P(false), // Enable P2P networking
-		node.WithStorePath(cfg.DefraDB.Store.Path),
-		http.WithAddress(defraUrl),
+		storeOption(cfg),
+		http.WithAddress(apiAddress),
 		node.WithNodeIdentity(identity.Identity(nodeIdentity)),
 	}
 
@@ -301,15 +342,46 @@ func StartDefraInstance(cfg *config.Config, schemaApplier SchemaApplier, collect
 		return nil, fmt.Errorf("failed to create defra node: %v ", err)
 	}
 
+	// From here on, any failure must close the node so that its store, ports and goroutines aren't leaked
+	started := false
+	defer func() {
+		if !started {
+			// ctx may already be cancelled, so don't use it for cleanup
+			if err := defraNode.Close(context.Background()); err != nil {
+				logger.Sugar.Warnf("Failed to close defra node after failed start: %v", err)
+			}
+		}
+	}()
+
 	err = defraNode.Start(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start defra node: %v ", err)
+		return nil, fmt.Errorf("failed to start defra node: %w", err)
 	}
 
-	// Connect to bootstrap peers
-	err = connectToPeers(ctx, defraNode, cfg.DefraDB.P2P.BootstrapPeers)
+	// Gate the P2P host before connecting to anyone, then connect to bootstrap peers, and those remembered from earlier
+	// runs, that the peer filter allows; unreachable ones are retried in the background once the node is up
+	filter, err := NewPeerFilter(cfg.DefraDB.P2P.PeerFilter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer filter config: %w", err)
+	}
+	if err := enforcePeerFilter(defraNode, filter); err != nil {
+		return nil, fmt.Errorf("failed to enforce peer filter: %w", err)
+	}
+	book, err := OpenPeerBook(peerBookPath(cfg.DefraDB.Store.Path, cfg.DefraDB.Store.InMemory))
+	if err != nil {
+		return nil, err
+	}
+	fromBook := cfg.DefraDB.P2P.PeerBookReconnect
+	if fromBook == 0 {
+		fromBook = DefaultPeerBookReconnect
+	}
+	bootstrap := newBootstrapper(defraNode, cfg.DefraDB.P2P.BootstrapPeers, book, fromBook, filter)
+	err = bootstrap.connect(ctx, cfg.DefraDB.P2P.BootstrapAttempts, cfg.DefraDB.P2P.MinConnectedPeers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to any peers, with error: %w", err)
+		return nil, fmt.Errorf("failed to connect to bootstrap peers: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("start cancelled: %w", err)
 	}
 
 	err = schemaApplier.ApplySchema(ctx, defraNode)
@@ -317,7 +389,6 @@ func StartDefraInstance(cfg *config.Config, schemaApplier SchemaApplier, collect
 		if strings.Contains(err.Error(), "collection already exists") {
 			logger.Sugar.Warnf("Failed to apply schema: %v\nProceeding...", err)
 		} else {
-			defer defraNode.Close(ctx)
 			return nil, fmt.Errorf("failed to apply schema: %v", err)
 		}
 	}
@@ -326,24 +397,59 @@ func StartDefraInstance(cfg *config.Config, schemaApplier SchemaApplier, collect
 	if err != nil {
 		return nil, fmt.Errorf("failed to add collections of interest %v: %w", collectionsOfInterest, err)
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("start cancelled: %w", err)
+	}
+
+	reconnectInterval := cfg.DefraDB.P2P.ReconnectInterval
+	if reconnectInterval == 0 {
+		reconnectInterval = DefaultReconnectInterval
+	}
+	if reconnectInterval > 0 {
+		go bootstrap.reconnect(reconnectInterval)
+	}
+	var discovery *mdnsDiscovery
+	if cfg.DefraDB.P2P.MDNS.Enabled {
+		discovery, err = startMDNS(defraNode, cfg, book, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start mDNS discovery: %w", err)
+		}
+	}
 
-	return defraNode, nil
+	started = true
+	managed := &Node{Node: defraNode, bootstrap: bootstrap, book: book, filter: filter, discovery: discovery}
+	if cfg.DefraDB.Store.InMemory {
+		managed.keyring = kr
+	}
+	return managed, nil
 }
 
-// A simple wrapper on StartDefraInstance that changes the configured defra store path to a temp directory for the test
+// A simple wrapper on StartDefraInstance that changes the configured defra store path to a temp directory for the test.
+// Set cfg.DefraDB.Store.InMemory to skip the filesystem entirely.
 func StartDefraInstanceWithTestConfig(t *testing.T, cfg *config.Config, schemaApplier SchemaApplier, collectionsOfInterest ...string) (*node.Node, error) {
-	ipAddress, err := networking.GetLANIP()
+	defraNode, err := StartNodeWithTestConfig(t, cfg, schemaApplier, collectionsOfInterest...)
 	if err != nil {
 		return nil, err
 	}
-	listenAddress := fmt.Sprintf("/ip4/%s/tcp/0", ipAddress)
-	defraUrl := fmt.Sprintf("%s:0", ipAddress)
+	return defraNode.Node, nil
+}
+
+// StartNodeWithTestConfig is StartDefraInstanceWithTestConfig, returning the Node handle from StartNode
+func StartNodeWithTestConfig(t *testing.T, cfg *config.Config, schemaApplier SchemaApplier, collectionsOfInterest ...string) (*Node, error) {
 	if cfg == nil {
 		cfg = DefaultConfig
 	}
-	cfg.DefraDB.Store.Path = t.TempDir()
+	ipAddress, err := lanIP(cfg)
+	if err != nil {
+		return nil, err
+	}
+	listenAddress := fmt.Sprintf("/%s/%s/tcp/0", ipProtocol(ipAddress), ipAddress)
+	defraUrl := net.JoinHostPort(ipAddress, "0")
+	if !cfg.DefraDB.Store.InMemory {
+		cfg.DefraDB.Store.Path = t.TempDir()
+	}
 	cfg.DefraDB.Url = defraUrl
 	cfg.DefraDB.P2P.ListenAddr = listenAddress
 	cfg.DefraDB.KeyringSecret = "testSecret"
-	return StartDefraInstance(cfg, schemaApplier, collectionsOfInterest...)
+	return StartNode(context.Background(), cfg, schemaApplier, collectionsOfInterest...)
 }
@@ -35,6 +35,30 @@ func TestStartDefraUsingConfig(t *testing.T) {
 	myNode.Close(context.Background())
 }
 
+func TestStartCleansUpOnFailure(t *testing.T) {
+	testConfig := *DefaultConfig
+	testConfig.DefraDB.Url = "127.0.0.1:0"
+	testConfig.DefraDB.P2P.ListenAddr = "/ip4/127.0.0.1/tcp/0"
+	testConfig.DefraDB.Store.Path = t.TempDir()
+	testConfig.DefraDB.KeyringSecret = "testSecret"
+
+	cancelled, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err := Start(cancelled, &testConfig, &MockSchemaApplierThatSucceeds{})
+	require.Error(t, err)
+
+	_, err = Start(t.Context(), &testConfig, NewSchemaApplierFromProvidedSchema("type User {"))
+	require.Error(t, err)
+
+	_, err = Start(t.Context(), &testConfig, &MockSchemaApplierThatSucceeds{}, "CollectionThatDoesNotExist")
+	require.Error(t, err)
+
+	// Each failed start closed its node, so the store is free to be opened again
+	myNode, err := Start(t.Context(), &testConfig, &MockSchemaApplierThatSucceeds{})
+	require.NoError(t, err)
+	require.NoError(t, myNode.Close(t.Context()))
+}
+
 func TestSubsequentRestartsYieldTheSameIdentity(t *testing.T) {
 	testConfig := DefaultConfig
 	testConfig.DefraDB.KeyringSecret = "testSecret"
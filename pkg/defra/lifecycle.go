@@ -0,0 +1,35 @@
+package defra
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sourcenetwork/defradb/event"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// untilClosed returns a context that is cancelled once defraNode is closed, for background work that should live as
+// long as the node. DefraDB has no close hook, but closing the node closes its event bus, and with it every
+// subscription, so we watch a subscription for that.
+func untilClosed(defraNode *node.Node) (context.Context, error) {
+	if IsRemote(defraNode) {
+		return nil, fmt.Errorf("watching for close: %w", ErrNotSupportedRemotely)
+	}
+	bus := defraNode.DB.Events()
+	if bus == nil {
+		return nil, fmt.Errorf("node has no event bus")
+	}
+	sub, err := bus.Subscribe(event.PeerInfoName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// Keep draining, as the bus blocks while a subscriber's buffer is full
+		for range sub.Message() {
+		}
+		cancel()
+	}()
+	return ctx, nil
+}
@@ -0,0 +1,222 @@
+package defra
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/zeroconf/v2"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/shinzonetwork/app-sdk/pkg/logger"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// DefaultMDNSServiceTag is the mDNS service tag nodes advertise and look for, unless configured
+const DefaultMDNSServiceTag = "shinzo"
+
+const (
+	mdnsDomain = "local"
+	// dnsaddrPrefix marks the TXT records carrying a node's multiaddrs, the same format libp2p's mDNS discovery uses
+	dnsaddrPrefix = "dnsaddr="
+)
+
+// DNS-SD service names are limited to 15 characters
+var validServiceTag = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,13}[A-Za-z0-9])?$`)
+
+// DiscoveredPeer is a node found on the local network over mDNS
+type DiscoveredPeer struct {
+	ID string
+	// Addresses are the peer's advertised addresses, in bootstrap peer form (<multiaddr>/p2p/<peer ID>)
+	Addresses []string
+	// Connected reports whether the most recent connection attempt, made each time the peer is seen, succeeded
+	Connected bool
+	LastError error
+	LastSeen  time.Time
+}
+
+// mdnsDiscovery advertises a node on the local network and connects it to the other nodes advertising the same
+// service tag, until the node is closed
+type mdnsDiscovery struct {
+	defraNode *node.Node
+	book      *PeerBook
+	filter    *PeerFilter
+	self      peer.ID
+
+	mu    sync.Mutex
+	found map[peer.ID]*DiscoveredPeer
+}
+
+// mdnsServiceName returns the mDNS service nodes with the given tag advertise, e.g. _shinzo._udp
+func mdnsServiceName(tag string) (string, error) {
+	if tag == "" {
+		tag = DefaultMDNSServiceTag
+	}
+	if !validServiceTag.MatchString(tag) {
+		return "", fmt.Errorf("invalid mDNS service tag %q: must be up to 15 letters, digits or hyphens", tag)
+	}
+	return fmt.Sprintf("_%s._udp", tag), nil
+}
+
+// startMDNS begins advertising defraNode over mDNS, and connecting to the other nodes it finds, in the background.
+// Peers it connects to are remembered in book.
+func startMDNS(defraNode *node.Node, cfg *config.Config, book *PeerBook, filter *PeerFilter) (*mdnsDiscovery, error) {
+	service, err := mdnsServiceName(cfg.DefraDB.P2P.MDNS.ServiceTag)
+	if err != nil {
+		return nil, err
+	}
+	announced, err := AnnouncedPeers(defraNode, cfg)
+	if err != nil {
+		return nil, err
+	}
+	self, addrs, err := parsePeerAddresses(announced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse announced addresses: %w", err)
+	}
+	ips, port := advertisedIPsAndPort(addrs)
+	if len(ips) == 0 {
+		// mDNS requires A or AAAA records, even though peers only read the TXT records
+		ip, err := lanIP(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get LAN IP address: %w", err)
+		}
+		ips = []string{ip}
+	}
+	txts := make([]string, 0, len(announced))
+	for _, address := range announced {
+		txts = append(txts, dnsaddrPrefix+address)
+	}
+
+	ctx, err := untilClosed(defraNode)
+	if err != nil {
+		return nil, err
+	}
+	// Peer IDs are unique and short enough to serve as the mDNS instance and host names
+	server, err := zeroconf.RegisterProxy(self.String(), service, mdnsDomain, port, self.String(), ips, txts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to advertise %s over mDNS: %w", service, err)
+	}
+
+	d := &mdnsDiscovery{defraNode: defraNode, book: book, filter: filter, self: self, found: map[peer.ID]*DiscoveredPeer{}}
+	entries := make(chan *zeroconf.ServiceEntry, 100)
+	go func() {
+		// Browse closes entries once ctx is done
+		for entry := range entries {
+			d.handleEntry(ctx, entry)
+		}
+	}()
+	go func() {
+		if err := zeroconf.Browse(ctx, service, mdnsDomain, entries); err != nil {
+			logger.Sugar.Warnf("mDNS discovery of %s stopped: %v", service, err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		server.Shutdown()
+	}()
+
+	logger.Sugar.Infof("Discovering peers on the local network as %s", service)
+	return d, nil
+}
+
+// handleEntry connects to the node behind an mDNS service entry, on the addresses the peer filter allows, unless it's
+// this node or the filter refuses it.
+// It dials every time a peer is seen, so that a peer which dropped is reconnected when it's next announced;
+// connecting to a peer that's still connected returns straight away.
+func (d *mdnsDiscovery) handleEntry(ctx context.Context, entry *zeroconf.ServiceEntry) {
+	var addresses []string
+	for _, txt := range entry.Text {
+		if address, ok := strings.CutPrefix(txt, dnsaddrPrefix); ok {
+			addresses = append(addresses, address)
+		}
+	}
+	id, _, err := parsePeerAddresses(addresses)
+	if err != nil {
+		logger.Sugar.Debugf("Ignoring mDNS entry %s: %v", entry.Instance, err)
+		return
+	}
+	if id == d.self {
+		return
+	}
+
+	d.mu.Lock()
+	discovered, known := d.found[id]
+	if !known {
+		discovered = &DiscoveredPeer{ID: id.String()}
+		d.found[id] = discovered
+	}
+	discovered.Addresses = addresses
+	discovered.LastSeen = time.Now()
+	d.mu.Unlock()
+
+	dialAddresses := addresses
+	if d.filter != nil {
+		dialAddresses, err = d.filter.allowedAddresses(addresses)
+	}
+	if err == nil {
+		dialCtx, cancel := context.WithTimeout(ctx, peerDialTimeout)
+		defer cancel()
+		err = d.defraNode.DB.Connect(dialCtx, dialAddresses)
+	}
+	if err != nil {
+		logger.Sugar.Debugf("Failed to connect to discovered peer %s: %v", id, err)
+	} else {
+		logger.Sugar.Infof("Connected to peer %s, discovered on the local network", id)
+		if bookErr := d.book.recordConnected(addresses); bookErr != nil {
+			logger.Sugar.Debugf("Failed to update peer book for %s: %v", id, bookErr)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	discovered.Connected = err == nil
+	discovered.LastError = err
+}
+
+// Peers returns a snapshot of the peers discovered so far, ordered by ID
+func (d *mdnsDiscovery) Peers() []DiscoveredPeer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	peers := make([]DiscoveredPeer, 0, len(d.found))
+	for _, discovered := range d.found {
+		peers = append(peers, *discovered)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].ID < peers[j].ID
+	})
+	return peers
+}
+
+// advertisedIPsAndPort picks the first IPv4 and IPv6 address, and the first TCP port, from a node's addresses for the
+// mDNS A/AAAA and SRV records
+func advertisedIPsAndPort(addrs []ma.Multiaddr) ([]string, int) {
+	var ip4, ip6 string
+	port := 0
+	for _, addr := range addrs {
+		if value, err := addr.ValueForProtocol(ma.P_IP4); err == nil && ip4 == "" {
+			ip4 = value
+		}
+		if value, err := addr.ValueForProtocol(ma.P_IP6); err == nil && ip6 == "" {
+			ip6 = value
+		}
+		if value, err := addr.ValueForProtocol(ma.P_TCP); err == nil && port == 0 {
+			port, _ = strconv.Atoi(value)
+		}
+	}
+	var ips []string
+	for _, ip := range []string{ip4, ip6} {
+		if ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	if port == 0 {
+		port = 9171 // Only the TXT records matter to peers, but the SRV record needs some port
+	}
+	return ips, port
+}
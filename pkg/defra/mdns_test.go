@@ -0,0 +1,95 @@
+package defra
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMDNSServiceName(t *testing.T) {
+	service, err := mdnsServiceName("")
+	require.NoError(t, err)
+	require.Equal(t, "_shinzo._udp", service)
+
+	service, err = mdnsServiceName("office-2")
+	require.NoError(t, err)
+	require.Equal(t, "_office-2._udp", service)
+
+	for _, invalid := range []string{"-office", "office_2", "has space", "sixteen-chars-xx"} {
+		_, err := mdnsServiceName(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestAdvertisedIPsAndPort(t *testing.T) {
+	addrs := []ma.Multiaddr{
+		ma.StringCast("/dns4/example.com/tcp/4000"),
+		ma.StringCast("/ip6/fe80::1/tcp/9172"),
+		ma.StringCast("/ip4/192.168.1.5/tcp/9171"),
+		ma.StringCast("/ip4/10.0.0.5/tcp/9173"),
+	}
+	ips, port := advertisedIPsAndPort(addrs)
+	require.Equal(t, []string{"192.168.1.5", "fe80::1"}, ips)
+	require.Equal(t, 4000, port)
+
+	ips, port = advertisedIPsAndPort(nil)
+	require.Empty(t, ips)
+	require.Equal(t, 9171, port)
+}
+
+func TestMDNSDiscovery(t *testing.T) {
+	// A tag unique to this run, so that nodes from other test runs on the network aren't found
+	tag := fmt.Sprintf("test-%d", time.Now().UnixNano()%1_000_000_000)
+	mdnsConfig := func(tag string) *config.Config {
+		cfg := syncTestConfig()
+		cfg.DefraDB.P2P.MDNS = config.MDNSConfig{Enabled: true, ServiceTag: tag}
+		return cfg
+	}
+
+	schema := NewSchemaApplierFromProvidedSchema(`type User { name: String }`)
+	writer, err := Start(t.Context(), mdnsConfig(tag), schema, "User")
+	require.NoError(t, err)
+	defer writer.Close(t.Context())
+	reader, err := StartNode(t.Context(), mdnsConfig(tag), schema, "User")
+	require.NoError(t, err)
+	defer reader.Close(t.Context())
+	other, err := StartNode(t.Context(), mdnsConfig(tag+"x"), schema, "User")
+	require.NoError(t, err)
+	defer other.Close(t.Context())
+
+	require.Eventually(t, func() bool {
+		discoveredPeers, err := reader.DiscoveredPeers()
+		require.NoError(t, err)
+		for _, discovered := range discoveredPeers {
+			if discovered.Connected {
+				return true
+			}
+		}
+		return false
+	}, 20*time.Second, 100*time.Millisecond, "nodes sharing a service tag should find each other")
+
+	type userWithDocID struct {
+		DocID string `json:"_docID"`
+	}
+	created, err := PostMutation[userWithDocID](t.Context(), writer, `mutation { create_User(input: {name: "Quinn"}) { _docID } }`)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+	defer cancel()
+	require.NoError(t, WaitForDoc(ctx, reader.Node, created.DocID))
+
+	otherPeerInfo, err := other.DB.PeerInfo()
+	require.NoError(t, err)
+	discoveredPeers, err := reader.DiscoveredPeers()
+	require.NoError(t, err)
+	for _, discovered := range discoveredPeers {
+		require.NotContains(t, otherPeerInfo[0], discovered.ID, "nodes with a different service tag should not be found")
+	}
+	discoveredPeers, err = other.DiscoveredPeers()
+	require.NoError(t, err)
+	require.Empty(t, discoveredPeers)
+}
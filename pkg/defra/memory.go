@@ -0,0 +1,68 @@
+package defra
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/sourcenetwork/defradb/keyring"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// memoryKeyring is a keyring.Keyring that never touches the filesystem, used by in-memory nodes
+type memoryKeyring struct {
+	mu   sync.RWMutex
+	keys map[string][]byte
+}
+
+var _ keyring.Keyring = (*memoryKeyring)(nil)
+
+func newMemoryKeyring() *memoryKeyring {
+	return &memoryKeyring{keys: map[string][]byte{}}
+}
+
+func (k *memoryKeyring) Set(name string, key []byte) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[name] = append([]byte(nil), key...)
+	return nil
+}
+
+func (k *memoryKeyring) Get(name string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[name]
+	if !ok {
+		return nil, keyring.ErrNotFound
+	}
+	return append([]byte(nil), key...), nil
+}
+
+func (k *memoryKeyring) Delete(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[name]; !ok {
+		return keyring.ErrNotFound
+	}
+	delete(k.keys, name)
+	return nil
+}
+
+func (k *memoryKeyring) List() ([]string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	names := make([]string, 0, len(k.keys))
+	for name := range k.keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// storeOption selects DefraDB's in-memory store for in-memory nodes, otherwise the on-disk store at the configured path
+func storeOption(cfg *config.Config) node.StoreOpt {
+	if cfg.DefraDB.Store.InMemory {
+		return node.WithStoreType(node.MemoryStore)
+	}
+	return node.WithStorePath(cfg.DefraDB.Store.Path)
+}
@@ -0,0 +1,69 @@
+package defra
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sourcenetwork/defradb/keyring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryKeyring(t *testing.T) {
+	kr := newMemoryKeyring()
+
+	_, err := kr.Get("missing")
+	require.ErrorIs(t, err, keyring.ErrNotFound)
+	require.ErrorIs(t, kr.Delete("missing"), keyring.ErrNotFound)
+
+	require.NoError(t, kr.Set("b", []byte("second")))
+	require.NoError(t, kr.Set("a", []byte("first")))
+	key, err := kr.Get("a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("first"), key)
+
+	names, err := kr.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, names)
+
+	require.NoError(t, kr.Delete("a"))
+	_, err = kr.Get("a")
+	require.ErrorIs(t, err, keyring.ErrNotFound)
+}
+
+func TestStartInMemory(t *testing.T) {
+	storePath := t.TempDir()
+	testConfig := *DefaultConfig
+	testConfig.DefraDB.Url = "127.0.0.1:0"
+	testConfig.DefraDB.P2P.ListenAddr = "/ip4/127.0.0.1/tcp/0"
+	testConfig.DefraDB.Store.Path = storePath
+	testConfig.DefraDB.Store.InMemory = true
+	testConfig.DefraDB.KeyringSecret = "" // Not needed, the keyring never touches disk
+
+	myNode, err := StartNode(t.Context(), &testConfig, NewSchemaApplierFromProvidedSchema(`type User { name: String }`), "User")
+	require.NoError(t, err)
+	defer myNode.Close(t.Context())
+
+	_, err = PostMutation[TestUser](t.Context(), myNode.Node, `mutation { create_User(input: {name: "Alice"}) { name } }`)
+	require.NoError(t, err)
+	users, err := QueryArray[TestUser](t.Context(), myNode.Node, `query { User { name } }`)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	kr, ok := myNode.InMemoryKeyring()
+	require.True(t, ok)
+	_, err = kr.Get(nodeIdentityKeyName)
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(storePath)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestInMemoryKeyringIsOnlyForInMemoryNodes(t *testing.T) {
+	myNode, err := StartNodeWithTestConfig(t, nil, &MockSchemaApplierThatSucceeds{})
+	require.NoError(t, err)
+	defer myNode.Close(t.Context())
+
+	_, ok := myNode.InMemoryKeyring()
+	require.False(t, ok)
+}
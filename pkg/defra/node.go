@@ -0,0 +1,80 @@
+package defra
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sourcenetwork/defradb/keyring"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// ErrUnmanagedNode is returned when asking for peer state of a node that wasn't started by StartNode
+var ErrUnmanagedNode = errors.New("node was not started by defra.StartNode")
+
+// Node is a defra node started by StartNode, together with the peer connectivity this package manages for it in the
+// background. It embeds the DefraDB node, so DB, APIURL and Close work as on the node itself; pass Node.Node to
+// helpers that take a *node.Node. The background work stops when the node is closed.
+type Node struct {
+	*node.Node
+
+	// bootstrap is set for every node StartNode starts, so it also marks the node as managed by this package
+	bootstrap *bootstrapper
+	book      *PeerBook
+	filter    *PeerFilter
+	// discovery is set if mDNS discovery is enabled
+	discovery *mdnsDiscovery
+	// keyring is set for in-memory nodes, whose identity isn't on disk
+	keyring keyring.Keyring
+}
+
+// BootstrapPeerStatus returns the connection status of each of the node's configured bootstrap peers, followed by the
+// peers it reconnected to from its peer book
+func (n *Node) BootstrapPeerStatus() ([]PeerStatus, error) {
+	if n.bootstrap == nil {
+		return nil, n.unmanaged("bootstrap peer status")
+	}
+	return n.bootstrap.Status(), nil
+}
+
+// PeerBook returns the peers the node remembers across restarts
+func (n *Node) PeerBook() (*PeerBook, error) {
+	if n.book == nil {
+		return nil, n.unmanaged("peer book")
+	}
+	return n.book, nil
+}
+
+// PeerFilter returns the rules deciding which peers the node talks to (see config.PeerFilterConfig), which can be
+// changed while it runs
+func (n *Node) PeerFilter() (*PeerFilter, error) {
+	if n.filter == nil {
+		return nil, n.unmanaged("peer filter")
+	}
+	return n.filter, nil
+}
+
+// DiscoveredPeers returns the peers the node has found on the local network over mDNS (see config.MDNSConfig). It's
+// empty if mDNS discovery isn't enabled.
+func (n *Node) DiscoveredPeers() ([]DiscoveredPeer, error) {
+	if n.bootstrap == nil {
+		return nil, n.unmanaged("discovered peers")
+	}
+	if n.discovery == nil {
+		return nil, nil
+	}
+	return n.discovery.Peers(), nil
+}
+
+// InMemoryKeyring returns the keyring holding the identity of a node started with config.DefraStoreConfig.InMemory.
+// The second return value is false for any other node, whose keyring lives on disk.
+func (n *Node) InMemoryKeyring() (keyring.Keyring, bool) {
+	return n.keyring, n.keyring != nil
+}
+
+// unmanaged explains why the node has no peer state: it's a remote DefraDB, or was started outside this package
+func (n *Node) unmanaged(what string) error {
+	if IsRemote(n.Node) {
+		return fmt.Errorf("%s: %w", what, ErrNotSupportedRemotely)
+	}
+	return fmt.Errorf("%s: %w", what, ErrUnmanagedNode)
+}
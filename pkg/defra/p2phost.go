@@ -0,0 +1,77 @@
+package defra
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/sourcenetwork/defradb/node"
+	"github.com/sourcenetwork/go-p2p"
+	"github.com/sourcenetwork/immutable"
+)
+
+// enforcePeerFilter applies filter to the connections of defraNode's P2P host, and to the blocks it serves, until the
+// node is closed. DefraDB's own block access rules still apply to the peers the filter allows.
+func enforcePeerFilter(defraNode *node.Node, filter *PeerFilter) error {
+	p2pPeer, err := nodePeer(defraNode)
+	if err != nil {
+		return err
+	}
+	p2pHost, err := unexportedField[host.Host](p2pPeer, "host")
+	if err != nil {
+		return fmt.Errorf("failed to find the P2P host: %w", err)
+	}
+	previous, err := unexportedField[immutable.Option[p2p.BlockAccessFunc]](p2pPeer, "blockAccessFunc")
+	if err != nil {
+		return fmt.Errorf("failed to find the block access rules: %w", err)
+	}
+	ctx, err := untilClosed(defraNode)
+	if err != nil {
+		return err
+	}
+
+	p2pPeer.SetBlockAccessFunc(func(ctx context.Context, peerID string, c cid.Cid) bool {
+		if !filter.blocksAllowed(peerID) {
+			return false
+		}
+		return !previous.HasValue() || previous.Value()(ctx, peerID, c)
+	})
+	filter.attach(ctx, p2pHost)
+	return nil
+}
+
+// nodePeer returns the go-p2p peer DefraDB started for defraNode, which it doesn't expose
+func nodePeer(defraNode *node.Node) (*p2p.Peer, error) {
+	p2pPeer, err := unexportedField[node.Peer](defraNode, "peer")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the node's P2P peer: %w", err)
+	}
+	concrete, ok := p2pPeer.(*p2p.Peer)
+	if !ok || concrete == nil {
+		return nil, fmt.Errorf("node has no go-p2p peer, got %T", p2pPeer)
+	}
+	return concrete, nil
+}
+
+// unexportedField reads an unexported field of the struct that value points to. DefraDB and go-p2p don't expose the
+// P2P host or a way to configure it, so this is the only way to gate it; the field names are checked by the tests, so
+// an upgrade that moves them fails there rather than in production.
+func unexportedField[T any](value any, name string) (T, error) {
+	var zero T
+	pointer := reflect.ValueOf(value)
+	if pointer.Kind() != reflect.Pointer || pointer.IsNil() || pointer.Elem().Kind() != reflect.Struct {
+		return zero, fmt.Errorf("%T is not a pointer to a struct", value)
+	}
+	field := pointer.Elem().FieldByName(name)
+	if !field.IsValid() {
+		return zero, fmt.Errorf("%T has no field %s", value, name)
+	}
+	readable := reflect.NewAt(field.Type(), field.Addr().UnsafePointer()).Elem()
+	result, ok := readable.Interface().(T)
+	if !ok {
+		return zero, fmt.Errorf("field %s of %T holds %v, not a %s", name, value, readable, reflect.TypeFor[T]())
+	}
+	return result, nil
+}
@@ -0,0 +1,198 @@
+package defra
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// DefaultPeerBookReconnect is how many peers from the peer book are reconnected on startup, unless configured
+const DefaultPeerBookReconnect = 10
+
+// peerBookFile is the peer book's file name under the store path
+const peerBookFile = "peers.json"
+
+// PeerBookEntry is a peer this node has connected to, remembered across restarts
+type PeerBookEntry struct {
+	ID string `json:"id"`
+	// Addresses are every known address of the peer, in bootstrap peer form (<multiaddr>/p2p/<peer ID>)
+	Addresses []string `json:"addresses"`
+	// LastSeen is when the peer was last connected, or zero if it was added by hand and never connected
+	LastSeen time.Time `json:"last_seen"`
+	// Failures counts the failed connection attempts since the peer was last connected
+	Failures int `json:"failures"`
+}
+
+// PeerBook remembers the peers a node has connected to, so that they can be reconnected after a restart alongside
+// the configured bootstrap peers. It's saved as JSON under the store path, or kept in memory for in-memory nodes.
+type PeerBook struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]*PeerBookEntry
+}
+
+// OpenPeerBook loads the peer book saved at path, or starts an empty one if there is none yet. An empty path keeps the
+// peer book in memory only.
+func OpenPeerBook(path string) (*PeerBook, error) {
+	book := &PeerBook{path: path, entries: map[string]*PeerBookEntry{}}
+	if path == "" {
+		return book, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return book, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read peer book: %w", err)
+	}
+	var entries []*PeerBookEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse peer book %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		book.entries[entry.ID] = entry
+	}
+	return book, nil
+}
+
+// List returns every entry, healthiest first: fewest recent failures, then most recently seen
+func (b *PeerBook) List() []PeerBookEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries := make([]PeerBookEntry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		copied := *entry
+		copied.Addresses = append([]string(nil), entry.Addresses...)
+		entries = append(entries, copied)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Failures != entries[j].Failures {
+			return entries[i].Failures < entries[j].Failures
+		}
+		if !entries[i].LastSeen.Equal(entries[j].LastSeen) {
+			return entries[i].LastSeen.After(entries[j].LastSeen)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// Add records a peer from its addresses, in bootstrap peer form, merging them into any existing entry. The peer isn't
+// dialled; it's reconnected on the node's next start.
+func (b *PeerBook) Add(addresses ...string) error {
+	id, _, err := parsePeerAddresses(addresses)
+	if err != nil {
+		return fmt.Errorf("invalid peer addresses %v: %w", addresses, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entry(id, addresses)
+	return b.save()
+}
+
+// Evict forgets a peer, so that it's no longer reconnected on startup. Evicting an unknown peer is not an error.
+func (b *PeerBook) Evict(peerID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.entries[peerID]; !ok {
+		return nil
+	}
+	delete(b.entries, peerID)
+	return b.save()
+}
+
+// recordConnected notes a successful connection to the peer with the given addresses
+func (b *PeerBook) recordConnected(addresses []string) error {
+	id, _, err := parsePeerAddresses(addresses)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry := b.entry(id, addresses)
+	entry.LastSeen = time.Now().UTC()
+	entry.Failures = 0
+	return b.save()
+}
+
+// recordFailure notes a failed connection attempt to a peer already in the book
+func (b *PeerBook) recordFailure(addresses []string) error {
+	id, _, err := parsePeerAddresses(addresses)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.entries[id.String()]
+	if !ok {
+		return nil
+	}
+	entry.Failures++
+	return b.save()
+}
+
+// entry returns the peer's entry, creating it if needed, with the given addresses merged in. b.mu must be held.
+func (b *PeerBook) entry(id peer.ID, addresses []string) *PeerBookEntry {
+	entry, ok := b.entries[id.String()]
+	if !ok {
+		entry = &PeerBookEntry{ID: id.String()}
+		b.entries[id.String()] = entry
+	}
+	for _, address := range addresses {
+		if !containsString(entry.Addresses, address) {
+			entry.Addresses = append(entry.Addresses, address)
+		}
+	}
+	return entry
+}
+
+// save writes the peer book to its path, via a temporary file so that a crash can't leave it half written. b.mu must
+// be held.
+func (b *PeerBook) save() error {
+	if b.path == "" {
+		return nil
+	}
+	entries := make([]*PeerBookEntry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode peer book: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return fmt.Errorf("failed to create peer book directory: %w", err)
+	}
+	temp := b.path + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write peer book: %w", err)
+	}
+	if err := os.Rename(temp, b.path); err != nil {
+		return fmt.Errorf("failed to write peer book: %w", err)
+	}
+	return nil
+}
+
+// peerBookPath returns where a node's peer book is saved: under the store path, or nowhere for in-memory nodes
+func peerBookPath(storePath string, inMemory bool) string {
+	if inMemory {
+		return ""
+	}
+	return filepath.Join(storePath, peerBookFile)
+}
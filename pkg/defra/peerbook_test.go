@@ -0,0 +1,94 @@
+package defra
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerBookPersistsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store", peerBookFile)
+	book, err := OpenPeerBook(path)
+	require.NoError(t, err)
+	require.Empty(t, book.List())
+
+	lan := "/ip4/192.168.1.5/tcp/9171/p2p/" + testPeerID
+	public := "/dns4/peer.example.com/tcp/9171/p2p/" + testPeerID
+	require.NoError(t, book.Add(lan))
+	require.NoError(t, book.recordConnected([]string{public}))
+	require.NoError(t, book.Add("/ip4/192.168.1.6/tcp/9171/p2p/"+otherTestPeerID))
+	require.NoError(t, book.recordFailure([]string{"/ip4/192.168.1.6/tcp/9171/p2p/" + otherTestPeerID}))
+
+	reopened, err := OpenPeerBook(path)
+	require.NoError(t, err)
+	entries := reopened.List()
+	require.Len(t, entries, 2)
+	require.Equal(t, testPeerID, entries[0].ID, "the healthy peer should be listed first")
+	require.Equal(t, []string{lan, public}, entries[0].Addresses)
+	require.WithinDuration(t, time.Now(), entries[0].LastSeen, time.Minute)
+	require.Equal(t, 0, entries[0].Failures)
+	require.Equal(t, otherTestPeerID, entries[1].ID)
+	require.True(t, entries[1].LastSeen.IsZero())
+	require.Equal(t, 1, entries[1].Failures)
+
+	require.NoError(t, reopened.Evict(otherTestPeerID))
+	require.NoError(t, reopened.Evict(otherTestPeerID), "evicting an unknown peer is not an error")
+	reopened, err = OpenPeerBook(path)
+	require.NoError(t, err)
+	require.Len(t, reopened.List(), 1)
+}
+
+func TestPeerBookRejectsInvalidInput(t *testing.T) {
+	book, err := OpenPeerBook("")
+	require.NoError(t, err)
+	require.Error(t, book.Add())
+	require.Error(t, book.Add("/ip4/192.168.1.5/tcp/9171"), "a peer book entry needs a peer ID")
+	require.Error(t, book.Add("/ip4/192.168.1.5/tcp/9171/p2p/"+testPeerID, "/ip4/192.168.1.6/tcp/9171/p2p/"+otherTestPeerID))
+	require.NoError(t, book.recordFailure([]string{"/ip4/192.168.1.5/tcp/9171/p2p/" + testPeerID}))
+	require.Empty(t, book.List(), "failures to reach unknown peers aren't recorded")
+
+	path := filepath.Join(t.TempDir(), peerBookFile)
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0644))
+	_, err = OpenPeerBook(path)
+	require.Error(t, err)
+}
+
+func TestBootstrapperReconnectsPeerBookEntries(t *testing.T) {
+	book, err := OpenPeerBook("")
+	require.NoError(t, err)
+	configured := "/ip4/192.168.1.5/tcp/9171/p2p/" + testPeerID
+	require.NoError(t, book.Add("/ip4/10.0.0.5/tcp/9171/p2p/"+testPeerID))
+	require.NoError(t, book.Add("/ip4/192.168.1.6/tcp/9171/p2p/"+otherTestPeerID))
+
+	b := newBootstrapper(nil, []string{configured}, book, DefaultPeerBookReconnect, nil)
+	statuses := b.Status()
+	require.Len(t, statuses, 2, "a peer book entry for a bootstrap peer shouldn't be dialled twice")
+	require.Equal(t, PeerStatus{Address: configured, Source: PeerSourceBootstrap, State: PeerPending}, statuses[0])
+	require.Equal(t, PeerSourcePeerBook, statuses[1].Source)
+	require.Equal(t, "/ip4/192.168.1.6/tcp/9171/p2p/"+otherTestPeerID, statuses[1].Address)
+
+	require.Len(t, newBootstrapper(nil, []string{configured}, book, -1, nil).Status(), 1)
+}
+
+func TestPeerBookOfStartedNode(t *testing.T) {
+	storePath := t.TempDir()
+	cfg := syncTestConfig()
+	cfg.DefraDB.Store.InMemory = false
+	cfg.DefraDB.Store.Path = storePath
+	cfg.DefraDB.KeyringSecret = "testSecret"
+	cfg.DefraDB.P2P.PeerBookReconnect = -1
+	defraNode, err := StartNode(t.Context(), cfg, NewSchemaApplierFromProvidedSchema(`type User { name: String }`), "User")
+	require.NoError(t, err)
+	defer defraNode.Close(t.Context())
+
+	book, err := defraNode.PeerBook()
+	require.NoError(t, err)
+	require.NoError(t, book.Add("/ip4/192.168.1.5/tcp/9171/p2p/"+testPeerID))
+	require.FileExists(t, filepath.Join(storePath, peerBookFile))
+
+	_, err = (&Node{Node: defraNode.Node}).PeerBook()
+	require.ErrorIs(t, err, ErrUnmanagedNode)
+}
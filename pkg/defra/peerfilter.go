@@ -0,0 +1,324 @@
+package defra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/shinzonetwork/app-sdk/pkg/logger"
+)
+
+var (
+	// ErrPeerFiltered is returned when the peer filter refuses a peer: it's denied, not allowlisted, every address is in
+	// a denied network, or the node already has its maximum number of peers
+	ErrPeerFiltered = errors.New("peer refused by peer filter")
+	// ErrMaxPeersReached is returned, together with ErrPeerFiltered, when connecting to a peer would exceed the
+	// configured maximum
+	ErrMaxPeersReached = errors.New("maximum number of peers reached")
+)
+
+// PeerFilter decides which peers a node talks to, from config.PeerFilterConfig, and can be changed while the node runs.
+//
+// The SDK checks it before dialling bootstrap, peer book and mDNS peers, and only dials a peer's allowed addresses.
+// It's also enforced on the node's P2P host, for connections in either direction: a refused connection is closed as
+// soon as it's established, refused peers are denied blocks, and connected peers are dropped once a rule change
+// refuses them. The host is gated after the libp2p handshake, as go-p2p doesn't let the SDK install a libp2p
+// connection gater.
+type PeerFilter struct {
+	mu         sync.RWMutex
+	allowed    map[peer.ID]struct{}
+	denied     map[peer.ID]struct{}
+	deniedNets map[string]*net.IPNet
+	maxPeers   int
+	// host is the P2P host the filter is enforced on, if any
+	host host.Host
+}
+
+// NewPeerFilter builds a filter from the given rules, validating every peer ID and CIDR
+func NewPeerFilter(cfg config.PeerFilterConfig) (*PeerFilter, error) {
+	f := &PeerFilter{
+		allowed:    map[peer.ID]struct{}{},
+		denied:     map[peer.ID]struct{}{},
+		deniedNets: map[string]*net.IPNet{},
+	}
+	for _, id := range cfg.AllowedPeers {
+		if err := f.AllowPeer(id); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range cfg.DeniedPeers {
+		if err := f.DenyPeer(id); err != nil {
+			return nil, err
+		}
+	}
+	for _, cidr := range cfg.DeniedCIDRs {
+		if err := f.DenyCIDR(cidr); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.SetMaxPeers(cfg.MaxPeers); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// AllowPeer adds a peer ID to the allowlist. Once the allowlist has any entries, only those peers are allowed.
+func (f *PeerFilter) AllowPeer(peerID string) error {
+	id, err := decodePeerID(peerID)
+	if err != nil {
+		return err
+	}
+	f.update(func() {
+		f.allowed[id] = struct{}{}
+	})
+	return nil
+}
+
+// RemoveAllowedPeer removes a peer ID from the allowlist. Removing the last entry allows every peer again.
+func (f *PeerFilter) RemoveAllowedPeer(peerID string) error {
+	id, err := decodePeerID(peerID)
+	if err != nil {
+		return err
+	}
+	f.update(func() {
+		delete(f.allowed, id)
+	})
+	return nil
+}
+
+// DenyPeer adds a peer ID to the denylist, disconnecting it if it's connected
+func (f *PeerFilter) DenyPeer(peerID string) error {
+	id, err := decodePeerID(peerID)
+	if err != nil {
+		return err
+	}
+	f.update(func() {
+		f.denied[id] = struct{}{}
+	})
+	return nil
+}
+
+// RemoveDeniedPeer removes a peer ID from the denylist
+func (f *PeerFilter) RemoveDeniedPeer(peerID string) error {
+	id, err := decodePeerID(peerID)
+	if err != nil {
+		return err
+	}
+	f.update(func() {
+		delete(f.denied, id)
+	})
+	return nil
+}
+
+// DenyCIDR denies every address in a network, e.g. "10.0.0.0/8", closing connections to addresses in it
+func (f *PeerFilter) DenyCIDR(cidr string) error {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	f.update(func() {
+		f.deniedNets[ipNet.String()] = ipNet
+	})
+	return nil
+}
+
+// RemoveDeniedCIDR lifts a network's denial
+func (f *PeerFilter) RemoveDeniedCIDR(cidr string) error {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	f.update(func() {
+		delete(f.deniedNets, ipNet.String())
+	})
+	return nil
+}
+
+// SetMaxPeers caps how many peers the node is connected to; 0 means no limit. Lowering the cap refuses new peers
+// rather than dropping connected ones.
+func (f *PeerFilter) SetMaxPeers(maxPeers int) error {
+	if maxPeers < 0 {
+		return fmt.Errorf("invalid max peers %d: must not be negative", maxPeers)
+	}
+	f.update(func() {
+		f.maxPeers = maxPeers
+	})
+	return nil
+}
+
+// Rules returns the filter's current rules, in the form they're configured
+func (f *PeerFilter) Rules() config.PeerFilterConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	rules := config.PeerFilterConfig{MaxPeers: f.maxPeers}
+	for id := range f.allowed {
+		rules.AllowedPeers = append(rules.AllowedPeers, id.String())
+	}
+	for id := range f.denied {
+		rules.DeniedPeers = append(rules.DeniedPeers, id.String())
+	}
+	for cidr := range f.deniedNets {
+		rules.DeniedCIDRs = append(rules.DeniedCIDRs, cidr)
+	}
+	sort.Strings(rules.AllowedPeers)
+	sort.Strings(rules.DeniedPeers)
+	sort.Strings(rules.DeniedCIDRs)
+	return rules
+}
+
+// update changes the rules, then drops any connected peer they now refuse
+func (f *PeerFilter) update(change func()) {
+	f.mu.Lock()
+	change()
+	f.mu.Unlock()
+	f.disconnectRefused()
+}
+
+// allowedAddresses returns the addresses, in bootstrap peer form, that the SDK may dial for a peer. It returns an error
+// if the peer mustn't be dialled at all.
+func (f *PeerFilter) allowedAddresses(addresses []string) ([]string, error) {
+	id, addrs, err := parsePeerAddresses(addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if !f.peerAllowed(id) {
+		return nil, fmt.Errorf("%w: %s", ErrPeerFiltered, id)
+	}
+	allowed := make([]string, 0, len(addresses))
+	for i, addr := range addrs {
+		if f.addrAllowed(addr) {
+			allowed = append(allowed, addresses[i])
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, fmt.Errorf("%w: every address of %s is in a denied network", ErrPeerFiltered, id)
+	}
+	if f.host != nil && f.maxPeers > 0 && f.host.Network().Connectedness(id) != network.Connected &&
+		len(f.host.Network().Peers()) >= f.maxPeers {
+		return nil, fmt.Errorf("%w: %w", ErrPeerFiltered, ErrMaxPeersReached)
+	}
+	return allowed, nil
+}
+
+// attach enforces the filter on a P2P host until ctx is done: refused connections are closed as the host establishes
+// them, and connected peers are dropped when the rules change to refuse them
+func (f *PeerFilter) attach(ctx context.Context, h host.Host) {
+	notifee := &network.NotifyBundle{ConnectedF: f.connected}
+	f.mu.Lock()
+	f.host = h
+	f.mu.Unlock()
+	h.Network().Notify(notifee)
+	f.disconnectRefused()
+
+	go func() {
+		<-ctx.Done()
+		h.Network().StopNotify(notifee)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.host = nil
+	}()
+}
+
+// connected closes a connection the host has just established if the filter refuses it, or if it's to a new peer
+// beyond the maximum
+func (f *PeerFilter) connected(n network.Network, conn network.Conn) {
+	id := conn.RemotePeer()
+	f.mu.RLock()
+	refused := !f.connectionAllowed(conn)
+	if !refused && f.maxPeers > 0 {
+		// The new connection is already counted; only the first connection to a peer makes it a new peer
+		refused = len(n.ConnsToPeer(id)) == 1 && len(n.Peers()) > f.maxPeers
+	}
+	f.mu.RUnlock()
+	if refused {
+		logger.Sugar.Infof("Closing connection to %s at %s: refused by peer filter", id, conn.RemoteMultiaddr())
+		// The host notifies while it's still setting the connection up, so close it once that's done
+		go conn.Close()
+	}
+}
+
+// disconnectRefused closes the host's connections that the filter refuses
+func (f *PeerFilter) disconnectRefused() {
+	f.mu.RLock()
+	var refused []network.Conn
+	if f.host != nil {
+		for _, conn := range f.host.Network().Conns() {
+			if !f.connectionAllowed(conn) {
+				refused = append(refused, conn)
+			}
+		}
+	}
+	f.mu.RUnlock()
+	for _, conn := range refused {
+		logger.Sugar.Infof("Disconnecting %s at %s: refused by peer filter", conn.RemotePeer(), conn.RemoteMultiaddr())
+		if err := conn.Close(); err != nil {
+			logger.Sugar.Debugf("Failed to close connection to %s: %v", conn.RemotePeer(), err)
+		}
+	}
+}
+
+// blocksAllowed reports whether the node may send blocks to a peer, given its ID as a string
+func (f *PeerFilter) blocksAllowed(peerID string) bool {
+	id, err := peer.Decode(peerID)
+	if err != nil {
+		return false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.peerAllowed(id)
+}
+
+// connectionAllowed reports whether a connection passes the peer and network rules. f.mu must be held.
+func (f *PeerFilter) connectionAllowed(conn network.Conn) bool {
+	return f.peerAllowed(conn.RemotePeer()) && f.addrAllowed(conn.RemoteMultiaddr())
+}
+
+// peerAllowed reports whether a peer passes the allow and deny lists. f.mu must be held.
+func (f *PeerFilter) peerAllowed(id peer.ID) bool {
+	if _, denied := f.denied[id]; denied {
+		return false
+	}
+	if len(f.allowed) == 0 {
+		return true
+	}
+	_, allowed := f.allowed[id]
+	return allowed
+}
+
+// addrAllowed reports whether an address is outside every denied network. Addresses without an IP, e.g. /dns4, are
+// allowed, as they can't be checked until resolved. f.mu must be held.
+func (f *PeerFilter) addrAllowed(addr ma.Multiaddr) bool {
+	if len(f.deniedNets) == 0 {
+		return true
+	}
+	ip, err := manet.ToIP(addr)
+	if err != nil {
+		return true
+	}
+	for _, ipNet := range f.deniedNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func decodePeerID(peerID string) (peer.ID, error) {
+	id, err := peer.Decode(peerID)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %v", ErrInvalidPeerID, peerID, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,211 @@
+package defra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPeerFilterValidatesRules(t *testing.T) {
+	_, err := NewPeerFilter(config.PeerFilterConfig{AllowedPeers: []string{"not-a-peer-id"}})
+	require.ErrorIs(t, err, ErrInvalidPeerID)
+	_, err = NewPeerFilter(config.PeerFilterConfig{DeniedPeers: []string{"not-a-peer-id"}})
+	require.ErrorIs(t, err, ErrInvalidPeerID)
+	_, err = NewPeerFilter(config.PeerFilterConfig{DeniedCIDRs: []string{"10.0.0.0"}})
+	require.Error(t, err)
+	_, err = NewPeerFilter(config.PeerFilterConfig{MaxPeers: -1})
+	require.Error(t, err)
+
+	rules := config.PeerFilterConfig{
+		AllowedPeers: []string{testPeerID},
+		DeniedPeers:  []string{otherTestPeerID},
+		DeniedCIDRs:  []string{"10.1.2.3/8"},
+		MaxPeers:     5,
+	}
+	f, err := NewPeerFilter(rules)
+	require.NoError(t, err)
+	rules.DeniedCIDRs = []string{"10.0.0.0/8"}
+	require.Equal(t, rules, f.Rules())
+
+	require.NoError(t, f.SetMaxPeers(0))
+	require.Zero(t, f.Rules().MaxPeers)
+}
+
+func TestPeerFilterPeerRules(t *testing.T) {
+	allowed := "/ip4/192.168.1.5/tcp/9171/p2p/" + testPeerID
+	other := "/ip4/192.168.1.6/tcp/9171/p2p/" + otherTestPeerID
+	f, err := NewPeerFilter(config.PeerFilterConfig{})
+	require.NoError(t, err)
+	_, err = f.allowedAddresses([]string{allowed})
+	require.NoError(t, err)
+	_, err = f.allowedAddresses([]string{other})
+	require.NoError(t, err, "every peer is allowed without rules")
+	require.True(t, f.blocksAllowed(otherTestPeerID))
+
+	require.NoError(t, f.AllowPeer(testPeerID))
+	_, err = f.allowedAddresses([]string{allowed})
+	require.NoError(t, err)
+	_, err = f.allowedAddresses([]string{other})
+	require.ErrorIs(t, err, ErrPeerFiltered, "only allowlisted peers are allowed")
+	require.False(t, f.blocksAllowed(otherTestPeerID))
+
+	require.NoError(t, f.DenyPeer(testPeerID))
+	_, err = f.allowedAddresses([]string{allowed})
+	require.ErrorIs(t, err, ErrPeerFiltered, "denials take precedence over the allowlist")
+	require.False(t, f.blocksAllowed(testPeerID))
+
+	require.NoError(t, f.RemoveDeniedPeer(testPeerID))
+	require.NoError(t, f.RemoveAllowedPeer(testPeerID))
+	_, err = f.allowedAddresses([]string{other})
+	require.NoError(t, err)
+	require.Error(t, f.DenyPeer("not-a-peer-id"))
+	require.False(t, f.blocksAllowed("not-a-peer-id"))
+}
+
+func TestPeerFilterDeniedCIDRs(t *testing.T) {
+	f, err := NewPeerFilter(config.PeerFilterConfig{DeniedCIDRs: []string{"10.0.0.0/8", "fd00::/8"}})
+	require.NoError(t, err)
+
+	_, err = f.allowedAddresses([]string{"/ip4/10.2.3.4/tcp/9171/p2p/" + testPeerID})
+	require.ErrorIs(t, err, ErrPeerFiltered)
+	_, err = f.allowedAddresses([]string{"/ip6/fd00::1/tcp/9171/p2p/" + testPeerID})
+	require.ErrorIs(t, err, ErrPeerFiltered)
+	_, err = f.allowedAddresses([]string{"/dns4/peer.example.com/tcp/9171/p2p/" + testPeerID})
+	require.NoError(t, err, "unresolved addresses can't be checked")
+
+	denied := "/ip4/10.2.3.4/tcp/9171/p2p/" + testPeerID
+	lan := "/ip4/192.168.1.5/tcp/9171/p2p/" + testPeerID
+	addresses, err := f.allowedAddresses([]string{denied, lan})
+	require.NoError(t, err)
+	require.Equal(t, []string{lan}, addresses, "only a peer's allowed addresses are dialled")
+
+	require.NoError(t, f.RemoveDeniedCIDR("10.0.0.0/8"))
+	addresses, err = f.allowedAddresses([]string{denied})
+	require.NoError(t, err)
+	require.Equal(t, []string{denied}, addresses)
+}
+
+func TestBootstrapperSkipsFilteredPeers(t *testing.T) {
+	f, err := NewPeerFilter(config.PeerFilterConfig{DeniedPeers: []string{testPeerID}})
+	require.NoError(t, err)
+	address := "/ip4/192.168.1.5/tcp/9171/p2p/" + testPeerID
+	b := newBootstrapper(nil, []string{address}, nil, 0, f)
+
+	// The node is never dialled, so a nil node is fine
+	err = b.connect(t.Context(), 3, 0)
+	require.NoError(t, err)
+	status := b.Status()[0]
+	require.Equal(t, PeerBlocked, status.State)
+	require.ErrorIs(t, status.LastError, ErrPeerFiltered)
+	require.Zero(t, status.Attempts, "a blocked peer should not be dialled")
+
+	require.Error(t, b.connect(t.Context(), 3, 1), "blocked peers don't count as connected")
+}
+
+func TestPeerFilterOfStartedNode(t *testing.T) {
+	cfg := syncTestConfig()
+	cfg.DefraDB.P2P.BootstrapPeers = []string{deadPeer}
+	cfg.DefraDB.P2P.PeerFilter = config.PeerFilterConfig{DeniedCIDRs: []string{"127.0.0.0/8"}}
+	defraNode, err := StartNode(t.Context(), cfg, NewSchemaApplierFromProvidedSchema(`type User { name: String }`), "User")
+	require.NoError(t, err)
+	defer defraNode.Close(t.Context())
+
+	statuses, err := defraNode.BootstrapPeerStatus()
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	require.Equal(t, PeerBlocked, statuses[0].State)
+
+	f, err := defraNode.PeerFilter()
+	require.NoError(t, err)
+	require.Equal(t, []string{"127.0.0.0/8"}, f.Rules().DeniedCIDRs)
+	require.NotNil(t, p2pHost(t, defraNode), "the filter must be attached to the node's P2P host")
+
+	cfg = syncTestConfig()
+	cfg.DefraDB.P2P.PeerFilter.DeniedPeers = []string{"not-a-peer-id"}
+	_, err = Start(t.Context(), cfg, NewSchemaApplierFromProvidedSchema(`type User { name: String }`), "User")
+	require.ErrorIs(t, err, ErrInvalidPeerID)
+}
+
+func TestPeerFilterGatesInboundConnections(t *testing.T) {
+	gated := startFilterTestNode(t)
+	dialler := startFilterTestNode(t)
+	gatedInfo, err := gated.DB.PeerInfo()
+	require.NoError(t, err)
+	diallerID := nodePeerID(t, dialler)
+
+	f, err := gated.PeerFilter()
+	require.NoError(t, err)
+	require.NoError(t, f.DenyPeer(diallerID.String()))
+
+	// The handshake may complete before the connection is closed, so the dial itself may succeed
+	_ = dialler.DB.Connect(t.Context(), gatedInfo)
+	requireDisconnected(t, p2pHost(t, gated), diallerID)
+
+	require.NoError(t, f.RemoveDeniedPeer(diallerID.String()))
+	require.NoError(t, dialler.DB.Connect(t.Context(), gatedInfo))
+	require.Eventually(t, func() bool {
+		return p2pHost(t, gated).Network().Connectedness(diallerID) == network.Connected
+	}, 10*time.Second, 50*time.Millisecond)
+
+	// Denying a connected peer drops it
+	require.NoError(t, f.DenyPeer(diallerID.String()))
+	requireDisconnected(t, p2pHost(t, gated), diallerID)
+}
+
+func TestPeerFilterMaxPeers(t *testing.T) {
+	gated := startFilterTestNode(t)
+	first := startFilterTestNode(t)
+	second := startFilterTestNode(t)
+	gatedInfo, err := gated.DB.PeerInfo()
+	require.NoError(t, err)
+	secondInfo, err := second.DB.PeerInfo()
+	require.NoError(t, err)
+
+	f, err := gated.PeerFilter()
+	require.NoError(t, err)
+	require.NoError(t, f.SetMaxPeers(1))
+
+	require.NoError(t, first.DB.Connect(t.Context(), gatedInfo))
+	_ = second.DB.Connect(t.Context(), gatedInfo)
+	gatedHost := p2pHost(t, gated)
+	requireDisconnected(t, gatedHost, nodePeerID(t, second))
+	require.Equal(t, network.Connected, gatedHost.Network().Connectedness(nodePeerID(t, first)))
+
+	_, err = f.allowedAddresses(secondInfo)
+	require.ErrorIs(t, err, ErrMaxPeersReached, "the node shouldn't dial peers beyond the maximum")
+}
+
+func startFilterTestNode(t *testing.T) *Node {
+	defraNode, err := StartNode(t.Context(), syncTestConfig(), NewSchemaApplierFromProvidedSchema(`type User { name: String }`), "User")
+	require.NoError(t, err)
+	t.Cleanup(func() { defraNode.Close(t.Context()) })
+	return defraNode
+}
+
+// p2pHost reads the node's P2P host the way enforcePeerFilter does, failing if DefraDB or go-p2p renamed the fields
+func p2pHost(t *testing.T, defraNode *Node) host.Host {
+	p2pPeer, err := nodePeer(defraNode.Node)
+	require.NoError(t, err)
+	h, err := unexportedField[host.Host](p2pPeer, "host")
+	require.NoError(t, err)
+	return h
+}
+
+func nodePeerID(t *testing.T, defraNode *Node) peer.ID {
+	info, err := defraNode.DB.PeerInfo()
+	require.NoError(t, err)
+	id, _, err := parsePeerAddresses(info)
+	require.NoError(t, err)
+	return id
+}
+
+func requireDisconnected(t *testing.T, h host.Host, id peer.ID) {
+	require.Eventually(t, func() bool {
+		return h.Network().Connectedness(id) != network.Connected
+	}, 10*time.Second, 50*time.Millisecond)
+}
@@ -1,70 +1,189 @@
 package defra
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/sourcenetwork/defradb/client"
-	"github.com/sourcenetwork/defradb/node"
 )
 
+var (
+	// ErrInvalidMultiaddr is returned for addresses that aren't valid multiaddrs, e.g. "127.0.0.1:9171"
+	ErrInvalidMultiaddr = errors.New("invalid multiaddr")
+	// ErrMissingPeerID is returned for bootstrap peers without a trailing /p2p/<peer ID>, or peers with an empty ID
+	ErrMissingPeerID = errors.New("missing peer ID")
+	// ErrInvalidPeerID is returned for peer IDs that don't decode, or that disagree with the peer they're given for
+	ErrInvalidPeerID = errors.New("invalid peer ID")
+	// ErrNoPeerAddresses is returned for peers with no address to dial
+	ErrNoPeerAddresses = errors.New("no addresses")
+)
+
+// PeerAddressError reports a bootstrap peer or peer info that was skipped, and why. Use errors.Is with ErrInvalidMultiaddr,
+// ErrMissingPeerID, ErrInvalidPeerID or ErrNoPeerAddresses to tell the causes apart.
+type PeerAddressError struct {
+	// Index is the position of the offending peer in the input
+	Index int
+	// Address is the offending address, if the problem is with one address rather than the whole peer
+	Address string
+	Err     error
+}
+
+func (e *PeerAddressError) Error() string {
+	if e.Address == "" {
+		return fmt.Sprintf("peer at index %d is invalid and will be skipped: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("peer at index %d has an invalid address %q that will be skipped: %v", e.Index, e.Address, e.Err)
+}
+
+func (e *PeerAddressError) Unwrap() error {
+	return e.Err
+}
+
+// BootstrapIntoPeers parses bootstrap peers (<multiaddr>/p2p/<peer ID>) into peer infos. Any multiaddr is accepted,
+// including /dns4, /dnsaddr and /p2p-circuit addresses. Addresses sharing a peer ID are grouped into one peer info, in
+// the order the peers first appear. Invalid entries are skipped, with a *PeerAddressError for each.
 func BootstrapIntoPeers(configuredBootstrapPeers []string) ([]client.PeerInfo, []error) {
 	peers := []client.PeerInfo{}
-	errors := []error{}
+	errs := []error{}
+	indexByID := map[peer.ID]int{}
 
-	for i, peer := range configuredBootstrapPeers {
-		parts := strings.Split(peer, "/p2p/")
-		if len(parts) != 2 {
-			errors = append(errors, fmt.Errorf("peer at index %d is invalid and will be skipped. Given: %v", i, configuredBootstrapPeers))
+	for i, address := range configuredBootstrapPeers {
+		transport, id, err := splitBootstrapPeer(address)
+		if err != nil {
+			errs = append(errs, &PeerAddressError{Index: i, Err: err})
 			continue
 		}
-		address := parts[0]
-		peerID := parts[1]
 
-		peerInfo := client.PeerInfo{
-			Addresses: []string{address},
-			ID:        peerID,
+		index, seen := indexByID[id]
+		if !seen {
+			index = len(peers)
+			indexByID[id] = index
+			peers = append(peers, client.PeerInfo{ID: id.String()})
+		}
+		if !containsString(peers[index].Addresses, transport.String()) {
+			peers[index].Addresses = append(peers[index].Addresses, transport.String())
 		}
-		peers = append(peers, peerInfo)
 	}
 
-	return peers, errors
+	return peers, errs
 }
 
+// PeersIntoBootstrap formats peer infos as bootstrap peers, one <multiaddr>/p2p/<peer ID> per address of every peer.
+// Addresses may already end with the peer's own /p2p/<peer ID>. Invalid peers and addresses are skipped, with a
+// *PeerAddressError for each.
 func PeersIntoBootstrap(peers []client.PeerInfo) ([]string, []error) {
 	bootstrapPeers := []string{}
-	errors := []error{}
+	errs := []error{}
 
-	for i, peer := range peers {
-		if peer.ID == "" {
-			errors = append(errors, fmt.Errorf("peer at index %d has empty ID and will be skipped", i))
+	for i, info := range peers {
+		if info.ID == "" {
+			errs = append(errs, &PeerAddressError{Index: i, Err: ErrMissingPeerID})
+			continue
+		}
+		id, err := peer.Decode(info.ID)
+		if err != nil {
+			errs = append(errs, &PeerAddressError{Index: i, Err: fmt.Errorf("%w %q: %v", ErrInvalidPeerID, info.ID, err)})
+			continue
+		}
+		if len(info.Addresses) == 0 {
+			errs = append(errs, &PeerAddressError{Index: i, Err: ErrNoPeerAddresses})
 			continue
 		}
 
-		if len(peer.Addresses) == 0 {
-			errors = append(errors, fmt.Errorf("peer at index %d has no addresses and will be skipped", i))
+		addrInfo := peer.AddrInfo{ID: id}
+		for _, address := range info.Addresses {
+			addr, err := ma.NewMultiaddr(address)
+			if err != nil {
+				errs = append(errs, &PeerAddressError{Index: i, Address: address, Err: fmt.Errorf("%w: %v", ErrInvalidMultiaddr, err)})
+				continue
+			}
+			transport, addressID := peer.SplitAddr(addr)
+			if addressID != "" && addressID != id {
+				errs = append(errs, &PeerAddressError{Index: i, Address: address, Err: fmt.Errorf("%w: address is for peer %s", ErrInvalidPeerID, addressID)})
+				continue
+			}
+			if len(transport) == 0 {
+				errs = append(errs, &PeerAddressError{Index: i, Address: address, Err: ErrNoPeerAddresses})
+				continue
+			}
+			addrInfo.Addrs = append(addrInfo.Addrs, transport)
+		}
+		if len(addrInfo.Addrs) == 0 {
 			continue
 		}
 
-		// Use the first address if multiple addresses are provided
-		address := peer.Addresses[0]
-		bootstrapPeer := fmt.Sprintf("%s/p2p/%s", address, peer.ID)
-		bootstrapPeers = append(bootstrapPeers, bootstrapPeer)
+		addrs, err := peer.AddrInfoToP2pAddrs(&addrInfo)
+		if err != nil {
+			errs = append(errs, &PeerAddressError{Index: i, Err: fmt.Errorf("%w: %v", ErrInvalidPeerID, err)})
+			continue
+		}
+		for _, addr := range addrs {
+			if !containsString(bootstrapPeers, addr.String()) {
+				bootstrapPeers = append(bootstrapPeers, addr.String())
+			}
+		}
 	}
 
-	return bootstrapPeers, errors
+	return bootstrapPeers, errs
 }
 
-func connectToPeers(ctx context.Context, defraNode *node.Node, peers []string) error {
-	if len(peers) == 0 {
-		return nil
+// splitBootstrapPeer splits a bootstrap peer into the multiaddr to dial and the peer ID it must end with
+func splitBootstrapPeer(address string) (ma.Multiaddr, peer.ID, error) {
+	// Check the peer ID first, as the multiaddr parser would only report it as an invalid multiaddr
+	if index := strings.LastIndex(address, "/p2p/"); index >= 0 {
+		encoded := strings.TrimSuffix(address[index+len("/p2p/"):], "/")
+		if _, err := peer.Decode(encoded); err != nil && !strings.Contains(encoded, "/") {
+			return nil, "", fmt.Errorf("%w %q: %v", ErrInvalidPeerID, encoded, err)
+		}
 	}
 
-	err := defraNode.DB.Connect(ctx, peers)
+	addr, err := ma.NewMultiaddr(address)
 	if err != nil {
-		return fmt.Errorf("error connecting to peer: %v", err)
+		return nil, "", fmt.Errorf("%w %q: %v", ErrInvalidMultiaddr, address, err)
+	}
+	transport, id := peer.SplitAddr(addr)
+	if id == "" {
+		return nil, "", fmt.Errorf("%w: %s does not end with /p2p/<peer ID>", ErrMissingPeerID, address)
 	}
+	if len(transport) == 0 {
+		return nil, "", fmt.Errorf("%w: %s has no address to dial", ErrNoPeerAddresses, address)
+	}
+	return transport, id, nil
+}
 
-	return nil
+// parsePeerAddresses parses addresses in bootstrap peer form, which must all belong to the same peer
+func parsePeerAddresses(addresses []string) (peer.ID, []ma.Multiaddr, error) {
+	if len(addresses) == 0 {
+		return "", nil, ErrNoPeerAddresses
+	}
+	var id peer.ID
+	addrs := make([]ma.Multiaddr, 0, len(addresses))
+	for _, address := range addresses {
+		addr, err := ma.NewMultiaddr(address)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w %q: %v", ErrInvalidMultiaddr, address, err)
+		}
+		_, addressID := peer.SplitAddr(addr)
+		if addressID == "" {
+			return "", nil, fmt.Errorf("%w: %s does not end with /p2p/<peer ID>", ErrMissingPeerID, address)
+		}
+		if id != "" && addressID != id {
+			return "", nil, fmt.Errorf("%w: addresses belong to both %s and %s", ErrInvalidPeerID, id, addressID)
+		}
+		id = addressID
+		addrs = append(addrs, addr)
+	}
+	return id, addrs, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
 }
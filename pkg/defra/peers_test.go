@@ -1,7 +1,6 @@
 package defra
 
 import (
-	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -10,71 +9,129 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// Valid peer IDs, for addresses that are parsed but never dialled
+const (
+	testPeerID      = "12D3KooWLttXvtbokAphdVWL6hx7VEviDnHYwQs5SmAw1Y1yfcZT"
+	otherTestPeerID = "12D3KooWQYhTNQdmr3ArTeUHRYzFg94BKyTkoWBDWez9kSCVe2Xo"
+)
+
 func TestBootstrapIntoPeers(t *testing.T) {
 	tests := []struct {
 		name           string
 		input          []string
 		expectedPeers  []client.PeerInfo
-		expectedErrors int
+		expectedErrors []error
 	}{
 		{
 			name:  "valid single peer",
-			input: []string{"127.0.0.1:4001/p2p/12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8"},
+			input: []string{"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID},
 			expectedPeers: []client.PeerInfo{
 				{
-					Addresses: []string{"127.0.0.1:4001"},
-					ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
+					Addresses: []string{"/ip4/127.0.0.1/tcp/4001"},
+					ID:        testPeerID,
 				},
 			},
-			expectedErrors: 0,
 		},
 		{
 			name:  "valid multiple peers",
-			input: []string{"127.0.0.1:4001/p2p/12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8", "192.168.1.100:4002/p2p/12D3KooWEj8q4q5r6s7t8u9v0w1x2y3z4a5b6c7d8e9f0g1h2i3j4k5l6m"},
+			input: []string{"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID, "/ip4/192.168.1.100/tcp/4002/p2p/" + otherTestPeerID},
 			expectedPeers: []client.PeerInfo{
 				{
-					Addresses: []string{"127.0.0.1:4001"},
-					ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
+					Addresses: []string{"/ip4/127.0.0.1/tcp/4001"},
+					ID:        testPeerID,
 				},
 				{
-					Addresses: []string{"192.168.1.100:4002"},
-					ID:        "12D3KooWEj8q4q5r6s7t8u9v0w1x2y3z4a5b6c7d8e9f0g1h2i3j4k5l6m",
+					Addresses: []string{"/ip4/192.168.1.100/tcp/4002"},
+					ID:        otherTestPeerID,
 				},
 			},
-			expectedErrors: 0,
 		},
 		{
-			name:           "invalid peer format - missing /p2p/",
-			input:          []string{"127.0.0.1:4001"},
+			name: "addresses sharing a peer ID are grouped",
+			input: []string{
+				"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID,
+				"/ip4/192.168.1.100/tcp/4002/p2p/" + otherTestPeerID,
+				"/ip6/::1/tcp/4001/p2p/" + testPeerID,
+				"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID,
+			},
+			expectedPeers: []client.PeerInfo{
+				{
+					Addresses: []string{"/ip4/127.0.0.1/tcp/4001", "/ip6/::1/tcp/4001"},
+					ID:        testPeerID,
+				},
+				{
+					Addresses: []string{"/ip4/192.168.1.100/tcp/4002"},
+					ID:        otherTestPeerID,
+				},
+			},
+		},
+		{
+			name: "dns, dnsaddr and relayed addresses",
+			input: []string{
+				"/dns4/peer.example.com/tcp/4001/p2p/" + testPeerID,
+				"/dnsaddr/bootstrap.example.com/p2p/" + otherTestPeerID,
+				"/ip4/1.2.3.4/tcp/4001/p2p/" + otherTestPeerID + "/p2p-circuit/p2p/" + testPeerID,
+			},
+			expectedPeers: []client.PeerInfo{
+				{
+					Addresses: []string{"/dns4/peer.example.com/tcp/4001", "/ip4/1.2.3.4/tcp/4001/p2p/" + otherTestPeerID + "/p2p-circuit"},
+					ID:        testPeerID,
+				},
+				{
+					Addresses: []string{"/dnsaddr/bootstrap.example.com"},
+					ID:        otherTestPeerID,
+				},
+			},
+		},
+		{
+			name:           "not a multiaddr",
+			input:          []string{"127.0.0.1:4001/p2p/" + testPeerID},
 			expectedPeers:  []client.PeerInfo{},
-			expectedErrors: 1,
+			expectedErrors: []error{ErrInvalidMultiaddr},
 		},
 		{
-			name:           "invalid peer format - multiple /p2p/",
-			input:          []string{"127.0.0.1:4001/p2p/12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8/p2p/extra"},
+			name:           "missing peer ID",
+			input:          []string{"/ip4/127.0.0.1/tcp/4001"},
 			expectedPeers:  []client.PeerInfo{},
-			expectedErrors: 1,
+			expectedErrors: []error{ErrMissingPeerID},
 		},
 		{
-			name:           "empty input",
-			input:          []string{},
+			name:           "garbage peer ID",
+			input:          []string{"/ip4/127.0.0.1/tcp/4001/p2p/12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8"},
 			expectedPeers:  []client.PeerInfo{},
-			expectedErrors: 0,
+			expectedErrors: []error{ErrInvalidPeerID},
+		},
+		{
+			name:           "multiple /p2p/",
+			input:          []string{"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID + "/p2p/extra"},
+			expectedPeers:  []client.PeerInfo{},
+			expectedErrors: []error{ErrInvalidPeerID},
+		},
+		{
+			name:           "peer ID only",
+			input:          []string{"/p2p/" + testPeerID},
+			expectedPeers:  []client.PeerInfo{},
+			expectedErrors: []error{ErrNoPeerAddresses},
+		},
+		{
+			name:          "empty input",
+			input:         []string{},
+			expectedPeers: []client.PeerInfo{},
 		},
 		{
 			name:  "mixed valid and invalid peers",
-			input: []string{"127.0.0.1:4001/p2p/12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8", "invalid", "192.168.1.100:4002/p2p/12D3KooWEj8q4q5r6s7t8u9v0w1x2y3z4a5b6c7d8e9f0g1h2i3j4k5l6m"},
+			input: []string{"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID, "invalid", "/ip4/192.168.1.100/tcp/4002/p2p/" + otherTestPeerID},
 			expectedPeers: []client.PeerInfo{
 				{
-					Addresses: []string{"127.0.0.1:4001"},
-					ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
+					Addresses: []string{"/ip4/127.0.0.1/tcp/4001"},
+					ID:        testPeerID,
 				},
 				{
-					Addresses: []string{"192.168.1.100:4002"},
-					ID:        "12D3KooWEj8q4q5r6s7t8u9v0w1x2y3z4a5b6c7d8e9f0g1h2i3j4k5l6m",
+					Addresses: []string{"/ip4/192.168.1.100/tcp/4002"},
+					ID:        otherTestPeerID,
 				},
 			},
-			expectedErrors: 1,
+			expectedErrors: []error{ErrInvalidMultiaddr},
 		},
 	}
 
@@ -82,8 +139,11 @@ func TestBootstrapIntoPeers(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			peers, errors := BootstrapIntoPeers(tt.input)
 
-			if len(errors) != tt.expectedErrors {
-				t.Errorf("Expected %d errors, got %d", tt.expectedErrors, len(errors))
+			require.Len(t, errors, len(tt.expectedErrors))
+			for i, expectedErr := range tt.expectedErrors {
+				require.ErrorIs(t, errors[i], expectedErr)
+				var addressErr *PeerAddressError
+				require.ErrorAs(t, errors[i], &addressErr)
 			}
 
 			if len(peers) != len(tt.expectedPeers) {
@@ -131,28 +191,28 @@ func TestPeersIntoBootstrap(t *testing.T) {
 			name: "valid single peer",
 			input: []client.PeerInfo{
 				{
-					Addresses: []string{"127.0.0.1:4001"},
-					ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
+					Addresses: []string{"/ip4/127.0.0.1/tcp/4001"},
+					ID:        testPeerID,
 				},
 			},
-			expectedBootstrap: []string{"127.0.0.1:4001/p2p/12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8"},
+			expectedBootstrap: []string{"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID},
 			expectedErrors:    0,
 		},
 		{
 			name: "valid multiple peers",
 			input: []client.PeerInfo{
 				{
-					Addresses: []string{"127.0.0.1:4001"},
-					ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
+					Addresses: []string{"/ip4/127.0.0.1/tcp/4001"},
+					ID:        testPeerID,
 				},
 				{
-					Addresses: []string{"192.168.1.100:4002"},
-					ID:        "12D3KooWEj8q4q5r6s7t8u9v0w1x2y3z4a5b6c7d8e9f0g1h2i3j4k5l6m",
+					Addresses: []string{"/ip4/192.168.1.100/tcp/4002"},
+					ID:        otherTestPeerID,
 				},
 			},
 			expectedBootstrap: []string{
-				"127.0.0.1:4001/p2p/12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
-				"192.168.1.100:4002/p2p/12D3KooWEj8q4q5r6s7t8u9v0w1x2y3z4a5b6c7d8e9f0g1h2i3j4k5l6m",
+				"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID,
+				"/ip4/192.168.1.100/tcp/4002/p2p/" + otherTestPeerID,
 			},
 			expectedErrors: 0,
 		},
@@ -160,7 +220,7 @@ func TestPeersIntoBootstrap(t *testing.T) {
 			name: "peer with empty ID",
 			input: []client.PeerInfo{
 				{
-					Addresses: []string{"127.0.0.1:4001"},
+					Addresses: []string{"/ip4/127.0.0.1/tcp/4001"},
 					ID:        "",
 				},
 			},
@@ -168,12 +228,24 @@ func TestPeersIntoBootstrap(t *testing.T) {
 			expectedErrors:       1,
 			expectedErrorIndices: []int{0},
 		},
+		{
+			name: "peer with garbage ID",
+			input: []client.PeerInfo{
+				{
+					Addresses: []string{"/ip4/127.0.0.1/tcp/4001"},
+					ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
+				},
+			},
+			expectedBootstrap:    []string{},
+			expectedErrors:       1,
+			expectedErrorIndices: []int{0},
+		},
 		{
 			name: "peer with no addresses",
 			input: []client.PeerInfo{
 				{
 					Addresses: []string{},
-					ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
+					ID:        testPeerID,
 				},
 			},
 			expectedBootstrap:    []string{},
@@ -181,15 +253,31 @@ func TestPeersIntoBootstrap(t *testing.T) {
 			expectedErrorIndices: []int{0},
 		},
 		{
-			name: "peer with multiple addresses - uses first",
+			name: "peer with multiple addresses - keeps all",
 			input: []client.PeerInfo{
 				{
-					Addresses: []string{"127.0.0.1:4001", "192.168.1.100:4002", "10.0.0.1:4003"},
-					ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
+					Addresses: []string{"/ip4/127.0.0.1/tcp/4001", "/dns4/peer.example.com/tcp/4002", "/ip4/10.0.0.1/tcp/4003/p2p/" + testPeerID},
+					ID:        testPeerID,
 				},
 			},
-			expectedBootstrap: []string{"127.0.0.1:4001/p2p/12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8"},
-			expectedErrors:    0,
+			expectedBootstrap: []string{
+				"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID,
+				"/dns4/peer.example.com/tcp/4002/p2p/" + testPeerID,
+				"/ip4/10.0.0.1/tcp/4003/p2p/" + testPeerID,
+			},
+			expectedErrors: 0,
+		},
+		{
+			name: "invalid addresses are skipped",
+			input: []client.PeerInfo{
+				{
+					Addresses: []string{"127.0.0.1:4001", "/ip4/127.0.0.1/tcp/4001", "/ip4/10.0.0.1/tcp/4003/p2p/" + otherTestPeerID},
+					ID:        testPeerID,
+				},
+			},
+			expectedBootstrap:    []string{"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID},
+			expectedErrors:       2,
+			expectedErrorIndices: []int{0, 0},
 		},
 		{
 			name:              "empty input",
@@ -201,19 +289,19 @@ func TestPeersIntoBootstrap(t *testing.T) {
 			name: "mixed valid and invalid peers",
 			input: []client.PeerInfo{
 				{
-					Addresses: []string{"127.0.0.1:4001"},
-					ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
+					Addresses: []string{"/ip4/127.0.0.1/tcp/4001"},
+					ID:        testPeerID,
 				},
 				{
-					Addresses: []string{"192.168.1.100:4002"},
+					Addresses: []string{"/ip4/192.168.1.100/tcp/4002"},
 					ID:        "",
 				},
 				{
 					Addresses: []string{},
-					ID:        "12D3KooWEj8q4q5r6s7t8u9v0w1x2y3z4a5b6c7d8e9f0g1h2i3j4k5l6m",
+					ID:        otherTestPeerID,
 				},
 			},
-			expectedBootstrap:    []string{"127.0.0.1:4001/p2p/12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8"},
+			expectedBootstrap:    []string{"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID},
 			expectedErrors:       2,
 			expectedErrorIndices: []int{1, 2},
 		},
@@ -263,8 +351,9 @@ func TestPeersIntoBootstrap(t *testing.T) {
 func TestBootstrapIntoPeersAndBack(t *testing.T) {
 	// Test round-trip conversion
 	originalBootstrap := []string{
-		"127.0.0.1:4001/p2p/12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
-		"192.168.1.100:4002/p2p/12D3KooWEj8q4q5r6s7t8u9v0w1x2y3z4a5b6c7d8e9f0g1h2i3j4k5l6m",
+		"/ip4/127.0.0.1/tcp/4001/p2p/" + testPeerID,
+		"/dns4/peer.example.com/tcp/4001/p2p/" + testPeerID,
+		"/ip4/192.168.1.100/tcp/4002/p2p/" + otherTestPeerID,
 	}
 
 	// Convert bootstrap strings to peers
@@ -295,123 +384,21 @@ func TestBootstrapIntoPeersAndBack(t *testing.T) {
 	}
 }
 
-func TestConnectToPeers(t *testing.T) {
-	t.Run("nil node should panic", func(t *testing.T) {
-		ctx := context.Background()
-		peers := []client.PeerInfo{
-			{
-				Addresses: []string{"127.0.0.1:4001"},
-				ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
-			},
-		}
-
-		// This should panic with nil node
-		defer func() {
-			if r := recover(); r == nil {
-				t.Errorf("Expected function to panic with nil node, but it didn't")
-			}
-		}()
-
-		peerString, errors := PeersIntoBootstrap(peers)
-		require.Len(t, errors, 0)
-
-		connectToPeers(ctx, nil, peerString)
-	})
-
-	t.Run("empty peers list", func(t *testing.T) {
-		ctx := context.Background()
-		peers := []string{}
-
-		// This should not panic even with nil node since there are no peers to connect to
-		err := connectToPeers(ctx, nil, peers)
-
-		require.NoError(t, err)
-	})
-
-	t.Run("connect to valid peers", func(t *testing.T) {
-		ctx := context.Background()
-
-		// Start a test Defra node
-		testConfig := DefaultConfig
-		testNode, err := StartDefraInstanceWithTestConfig(t, testConfig, &MockSchemaApplierThatSucceeds{})
-		if err != nil {
-			t.Fatalf("Failed to start test Defra node: %v", err)
-		}
-		defer testNode.Close(ctx)
-
-		// Create some valid peer info (these will fail to connect since they're not real peers, but should not panic)
-		peers := []client.PeerInfo{
-			{
-				Addresses: []string{"127.0.0.1:4001"},
-				ID:        "12D3KooWBh1N2rLJc9Rj7Z3rX9Y8uMvN2pQ4sT7wX1yB6eF9hK3mP5sA8",
-			},
-			{
-				Addresses: []string{"192.168.1.100:4002"},
-				ID:        "12D3KooWEj8q4q5r6s7t8u9v0w1x2y3z4a5b6c7d8e9f0g1h2i3j4k5l6m",
-			},
-		}
-		peerStrings, errors := PeersIntoBootstrap(peers)
-		if len(errors) > 0 {
-			t.Errorf("Errors translating peers into bootstrap format: %v", errors)
-		}
-
-		// This should not panic and should return connection errors (since these are fake peers)
-		err = connectToPeers(ctx, testNode, peerStrings)
-		require.Error(t, err)
-	})
-
-	t.Run("connect to empty peers list with real node", func(t *testing.T) {
-		ctx := context.Background()
-
-		// Start a test Defra node
-		testConfig := DefaultConfig
-		testNode, err := StartDefraInstanceWithTestConfig(t, testConfig, &MockSchemaApplierThatSucceeds{})
-		if err != nil {
-			t.Fatalf("Failed to start test Defra node: %v", err)
-		}
-		defer testNode.Close(ctx)
-
-		peers := []string{}
-
-		// This should not panic and should return no errors
-		err = connectToPeers(ctx, testNode, peers)
-		require.NoError(t, err)
-	})
-
-	t.Run("connect multiple nodes to each other", func(t *testing.T) {
-		ctx := context.Background()
-
-		// Start first Defra node with a specific listen address
-		testConfig1 := DefaultConfig
-		testConfig1.DefraDB.P2P.ListenAddr = "/ip4/127.0.0.1/tcp/9171"
-		node1, err := StartDefraInstanceWithTestConfig(t, testConfig1, &MockSchemaApplierThatSucceeds{})
-		if err != nil {
-			t.Fatalf("Failed to start first Defra node: %v", err)
-		}
-		defer node1.Close(ctx)
-
-		// Start second Defra node with a different listen address
-		testConfig2 := DefaultConfig
-		testConfig2.DefraDB.P2P.ListenAddr = "/ip4/127.0.0.1/tcp/9172"
-		node2, err := StartDefraInstanceWithTestConfig(t, testConfig2, &MockSchemaApplierThatSucceeds{})
-		if err != nil {
-			t.Fatalf("Failed to start second Defra node: %v", err)
-		}
-		defer node2.Close(ctx)
-
-		// Get the peer info from node1 to connect node2 to it
-		node1PeerInfo, err := node1.DB.PeerInfo()
-		require.NoError(t, err)
-
-		// Now connect node2 to node1 using our connectToPeers function
-		err = connectToPeers(ctx, node2, node1PeerInfo)
-		require.NoError(t, err)
-
-		// Test connecting node1 to node2 as well (bidirectional connection)
-		node2PeerInfo, err := node2.DB.PeerInfo()
-		require.NoError(t, err)
-
-		err = connectToPeers(ctx, node1, node2PeerInfo)
-		require.NoError(t, err)
+func TestParsePeerAddresses(t *testing.T) {
+	id, addrs, err := parsePeerAddresses([]string{
+		"/ip4/192.168.1.5/tcp/9171/p2p/" + testPeerID,
+		"/ip6/fe80::1/tcp/9171/p2p/" + testPeerID,
 	})
+	require.NoError(t, err)
+	require.Equal(t, testPeerID, id.String())
+	require.Len(t, addrs, 2)
+
+	_, _, err = parsePeerAddresses(nil)
+	require.ErrorIs(t, err, ErrNoPeerAddresses)
+	_, _, err = parsePeerAddresses([]string{"/ip4/192.168.1.5/tcp/9171"})
+	require.ErrorIs(t, err, ErrMissingPeerID, "addresses without a peer ID can't be dialled")
+	_, _, err = parsePeerAddresses([]string{"not a multiaddr"})
+	require.ErrorIs(t, err, ErrInvalidMultiaddr)
+	_, _, err = parsePeerAddresses([]string{"/ip4/192.168.1.5/tcp/9171/p2p/" + testPeerID, "/ip4/192.168.1.6/tcp/9171/p2p/" + otherTestPeerID})
+	require.ErrorIs(t, err, ErrInvalidPeerID, "addresses must all be for the same peer")
 }
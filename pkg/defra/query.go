@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/sourcenetwork/defradb/client"
 	"github.com/sourcenetwork/defradb/node"
 )
 
@@ -27,12 +28,12 @@ func newQueryClient(defraNode *node.Node) (*queryClient, error) {
 }
 
 // query executes a GraphQL query using the Defra client directly and returns the raw result
-func (c *queryClient) query(ctx context.Context, query string) (interface{}, error) {
+func (c *queryClient) query(ctx context.Context, query string, opts ...client.RequestOption) (interface{}, error) {
 	if query == "" {
 		return nil, fmt.Errorf("query parameter is empty")
 	}
 
-	result := c.defraNode.DB.ExecRequest(ctx, query)
+	result := c.defraNode.DB.ExecRequest(ctx, query, opts...)
 	gqlResult := result.GQL
 
 	if len(gqlResult.Errors) > 0 {
@@ -92,8 +93,8 @@ func (c *queryClient) queryInto(ctx context.Context, query string, result interf
 
 // queryDataInto executes a GraphQL query and unmarshals only the "data" field into a struct
 // This function handles both single objects and arrays in the response
-func (c *queryClient) queryDataInto(ctx context.Context, query string, result interface{}) error {
-	data, err := c.query(ctx, query)
+func (c *queryClient) queryDataInto(ctx context.Context, query string, result interface{}, opts ...client.RequestOption) error {
+	data, err := c.query(ctx, query, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -234,3 +235,52 @@ func QueryArray[T any](ctx context.Context, defraNode *node.Node, query string)
 	err = client.queryDataInto(ctx, wrappedQuery, &result)
 	return result, err
 }
+
+// QuerySingleWithVariables executes a parameterised GraphQL query and returns a single item of the specified type
+// Variables are passed to DefraDB separately from the query, so values never need to be escaped into the query string
+func QuerySingleWithVariables[T any](ctx context.Context, defraNode *node.Node, query string, variables map[string]any) (T, error) {
+	var result T
+	queryClient, err := newQueryClient(defraNode)
+	if err != nil {
+		return result, err
+	}
+
+	wrappedQuery := wrapQueryIfNeeded(query)
+	err = queryClient.queryDataInto(ctx, wrappedQuery, &result, client.WithVariables(variables))
+	return result, err
+}
+
+// QueryArrayWithVariables executes a parameterised GraphQL query and returns an array of the specified type
+// Variables are passed to DefraDB separately from the query, so values never need to be escaped into the query string
+func QueryArrayWithVariables[T any](ctx context.Context, defraNode *node.Node, query string, variables map[string]any) ([]T, error) {
+	var result []T
+	queryClient, err := newQueryClient(defraNode)
+	if err != nil {
+		return result, err
+	}
+
+	wrappedQuery := wrapQueryIfNeeded(query)
+	err = queryClient.queryDataInto(ctx, wrappedQuery, &result, client.WithVariables(variables))
+	return result, err
+}
+
+// QueryWithVariables executes a parameterised GraphQL query and unmarshals its whole data object into the specified type,
+// keyed by top-level field. This is useful for queries that select several (possibly aliased) fields at once.
+func QueryWithVariables[T any](ctx context.Context, defraNode *node.Node, query string, variables map[string]any) (T, error) {
+	var result T
+	queryClient, err := newQueryClient(defraNode)
+	if err != nil {
+		return result, err
+	}
+
+	data, err := queryClient.query(ctx, wrapQueryIfNeeded(query), client.WithVariables(variables))
+	if err != nil {
+		return result, fmt.Errorf("failed to execute query: %w", err)
+	}
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal data: %w", err)
+	}
+	err = json.Unmarshal(dataBytes, &result)
+	return result, err
+}
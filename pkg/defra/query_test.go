@@ -2,6 +2,7 @@ package defra
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/shinzonetwork/app-sdk/pkg/config"
@@ -373,6 +374,68 @@ func TestQueryArray(t *testing.T) {
 	})
 }
 
+func TestQueryWithVariables(t *testing.T) {
+	defraNode, _ := setupTestQueryClient(t)
+	defer defraNode.Close(context.Background())
+
+	ctx := context.Background()
+
+	users := []string{"Alice", "Bob", `Charlie "Chuck"`}
+	for _, name := range users {
+		createUserQuery := `
+			mutation {
+				create_User(input: {name: "` + strings.ReplaceAll(name, `"`, `\"`) + `"}) {
+					name
+				}
+			}
+		`
+		_, err := PostMutation[TestUser](ctx, defraNode, createUserQuery)
+		require.NoError(t, err)
+	}
+
+	query := `
+		query($names: [String!]) {
+			User(filter: {name: {_in: $names}}) {
+				name
+			}
+		}
+	`
+
+	t.Run("array query with variables", func(t *testing.T) {
+		userArray, err := QueryArrayWithVariables[TestUser](ctx, defraNode, query, map[string]any{
+			"names": []any{"Alice", `Charlie "Chuck"`},
+		})
+		require.NoError(t, err)
+		assert.Len(t, userArray, 2)
+	})
+
+	t.Run("single query with variables", func(t *testing.T) {
+		user, err := QuerySingleWithVariables[TestUser](ctx, defraNode, query, map[string]any{
+			"names": []any{"Bob"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Bob", user.Name)
+	})
+
+	t.Run("aliased fields with variables", func(t *testing.T) {
+		fields, err := QueryWithVariables[map[string][]TestUser](ctx, defraNode, `
+			query($first: [String!], $second: [String!]) {
+				first: User(filter: {name: {_in: $first}}) {
+					name
+				}
+				second: User(filter: {name: {_in: $second}}) {
+					name
+				}
+			}
+		`, map[string]any{
+			"first":  []any{"Alice"},
+			"second": []any{"Bob", "Nobody"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]TestUser{"first": {{Name: "Alice"}}, "second": {{Name: "Bob"}}}, fields)
+	})
+}
+
 func TestQueryAutoWrapping(t *testing.T) {
 	defraNode, _ := setupTestQueryClient(t)
 	defer defraNode.Close(context.Background())
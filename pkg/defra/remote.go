@@ -0,0 +1,117 @@
+package defra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/shinzonetwork/app-sdk/pkg/logger"
+	"github.com/sourcenetwork/corekv"
+	"github.com/sourcenetwork/defradb/acp/dac"
+	"github.com/sourcenetwork/defradb/event"
+	"github.com/sourcenetwork/defradb/http"
+	"github.com/sourcenetwork/defradb/node"
+	"github.com/sourcenetwork/immutable"
+)
+
+// ErrNotSupportedRemotely is returned for operations that need direct access to an embedded node's internals
+var ErrNotSupportedRemotely = errors.New("not supported by a remote DefraDB")
+
+// remoteDB adapts DefraDB's HTTP client to the node.DB interface, so that a *node.Node backed by a remote DefraDB
+// can be passed to the same query, mutation and attestation helpers as an embedded one.
+// The remote node's rootstore and event bus are not reachable over HTTP; Rootstore and Events return nil, and the
+// helpers that need them check IsRemote and return ErrNotSupportedRemotely rather than misbehaving.
+type remoteDB struct {
+	*http.Client
+	url string
+}
+
+var _ node.DB = (*remoteDB)(nil)
+
+func (db *remoteDB) MaxTxnRetries() int {
+	return 0
+}
+
+func (db *remoteDB) Rootstore() corekv.TxnStore {
+	return nil
+}
+
+func (db *remoteDB) Events() event.Bus {
+	return nil
+}
+
+func (db *remoteDB) DocumentACP() immutable.Option[dac.DocumentACP] {
+	return immutable.None[dac.DocumentACP]()
+}
+
+func (db *remoteDB) PurgeDACState(ctx context.Context) error {
+	return fmt.Errorf("purging document ACP state of %s: %w", db.url, ErrNotSupportedRemotely)
+}
+
+func (db *remoteDB) PurgeNACState(ctx context.Context) error {
+	return fmt.Errorf("purging node ACP state of %s: %w", db.url, ErrNotSupportedRemotely)
+}
+
+func (db *remoteDB) GetNodeIdentityToken(ctx context.Context, audience immutable.Option[string]) ([]byte, error) {
+	return nil, fmt.Errorf("getting node identity token from %s: %w", db.url, ErrNotSupportedRemotely)
+}
+
+// Close is a no-op; the remote DefraDB keeps running
+func (db *remoteDB) Close() {}
+
+// IsRemote reports whether the node is backed by a remote DefraDB (see config.DefraModeRemote) rather than an embedded one
+func IsRemote(defraNode *node.Node) bool {
+	if defraNode == nil {
+		return false
+	}
+	_, ok := defraNode.DB.(*remoteDB)
+	return ok
+}
+
+// ConnectToRemote connects to a DefraDB running as a separate process, serving its HTTP API at url (e.g. "http://localhost:9181").
+// The returned node works with the query, mutation and attestation query helpers; closing it leaves the remote DefraDB running.
+// Helpers that need the node's internals - WaitForDoc and WaitForCommit (event bus), attestation.VerifyVersion (blockstore),
+// signer.LoadIdentity and the signing helpers (keyring), and the peer state of StartNode's Node - return ErrNotSupportedRemotely.
+func ConnectToRemote(ctx context.Context, url string) (*node.Node, error) {
+	if url == "" {
+		return nil, fmt.Errorf("remote DefraDB url cannot be empty")
+	}
+	httpClient, err := http.NewClient(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote DefraDB url %s: %w", url, err)
+	}
+	if err := httpClient.HealthCheck(ctx); err != nil {
+		return nil, fmt.Errorf("remote DefraDB at %s is unavailable: %w", url, err)
+	}
+	return &node.Node{DB: &remoteDB{Client: httpClient, url: url}, APIURL: url}, nil
+}
+
+// startRemote connects to the remote DefraDB configured at cfg.DefraDB.Url, then applies the schema and subscribes to the collections of interest on it
+func startRemote(ctx context.Context, cfg *config.Config, schemaApplier SchemaApplier, collectionsOfInterest ...string) (*node.Node, error) {
+	logger.Init(cfg.Logger.Development)
+
+	defraNode, err := ConnectToRemote(ctx, cfg.DefraDB.Url)
+	if err != nil {
+		return nil, err
+	}
+	logger.Sugar.Infof("Connected to remote DefraDB at %s", cfg.DefraDB.Url)
+
+	err = schemaApplier.ApplySchema(ctx, defraNode)
+	if err != nil {
+		if strings.Contains(err.Error(), "collection already exists") {
+			logger.Sugar.Warnf("Failed to apply schema: %v\nProceeding...", err)
+		} else {
+			return nil, fmt.Errorf("failed to apply schema: %v", err)
+		}
+	}
+
+	if len(collectionsOfInterest) > 0 {
+		err = defraNode.DB.AddP2PCollections(ctx, collectionsOfInterest...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add collections of interest %v: %w", collectionsOfInterest, err)
+		}
+	}
+	return defraNode, nil
+}
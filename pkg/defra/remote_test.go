@@ -0,0 +1,95 @@
+package defra
+
+import (
+	"testing"
+
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteMode(t *testing.T) {
+	ctx := t.Context()
+	embedded, err := StartDefraInstanceWithTestConfig(t, DefaultConfig, NewSchemaApplierFromProvidedSchema("type User { name: String }"))
+	require.NoError(t, err)
+	defer embedded.Close(ctx)
+	require.False(t, IsRemote(embedded))
+
+	remoteConfig := &config.Config{
+		DefraDB: config.DefraDBConfig{
+			Mode: config.DefraModeRemote,
+			Url:  embedded.APIURL,
+		},
+	}
+	remote, err := Start(ctx, remoteConfig, &MockSchemaApplierThatSucceeds{})
+	require.NoError(t, err)
+	require.True(t, IsRemote(remote))
+
+	// Writes over HTTP land in the remote DefraDB, and the same helpers read them back
+	created, err := PostMutation[TestUser](ctx, remote, `mutation { create_User(input: { name: "Quinn" }) { name } }`)
+	require.NoError(t, err)
+	require.Equal(t, "Quinn", created.Name)
+
+	users, err := QueryArray[TestUser](ctx, remote, `User { name }`)
+	require.NoError(t, err)
+	require.Equal(t, []TestUser{{Name: "Quinn"}}, users)
+
+	// Closing the remote handle leaves the DefraDB running
+	require.NoError(t, remote.Close(ctx))
+	users, err = QueryArray[TestUser](ctx, embedded, `User { name }`)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	_, err = ConnectToRemote(ctx, "http://127.0.0.1:1")
+	require.Error(t, err)
+
+	remoteConfig.DefraDB.Mode = "somewhere"
+	_, err = Start(ctx, remoteConfig, &MockSchemaApplierThatSucceeds{})
+	require.Error(t, err)
+}
+
+func TestRemoteModeRefusesHelpersNeedingNodeInternals(t *testing.T) {
+	ctx := t.Context()
+	embedded, err := StartDefraInstanceWithTestConfig(t, DefaultConfig, NewSchemaApplierFromProvidedSchema("type User { name: String }"))
+	require.NoError(t, err)
+	defer embedded.Close(ctx)
+	remoteConfig := &config.Config{
+		DefraDB: config.DefraDBConfig{
+			Mode: config.DefraModeRemote,
+			Url:  embedded.APIURL,
+		},
+	}
+	remote, err := StartNode(ctx, remoteConfig, &MockSchemaApplierThatSucceeds{})
+	require.NoError(t, err)
+	defer remote.Close(ctx)
+
+	t.Run("WaitForDoc", func(t *testing.T) {
+		require.ErrorIs(t, WaitForDoc(ctx, remote.Node, "bae-123"), ErrNotSupportedRemotely)
+	})
+	t.Run("WaitForCommit", func(t *testing.T) {
+		require.ErrorIs(t, WaitForCommit(ctx, remote.Node, "bafyreigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"), ErrNotSupportedRemotely)
+	})
+	t.Run("untilClosed", func(t *testing.T) {
+		_, err := untilClosed(remote.Node)
+		require.ErrorIs(t, err, ErrNotSupportedRemotely)
+	})
+	t.Run("BootstrapPeerStatus", func(t *testing.T) {
+		_, err := remote.BootstrapPeerStatus()
+		require.ErrorIs(t, err, ErrNotSupportedRemotely)
+	})
+	t.Run("PeerBook", func(t *testing.T) {
+		_, err := remote.PeerBook()
+		require.ErrorIs(t, err, ErrNotSupportedRemotely)
+	})
+	t.Run("PeerFilter", func(t *testing.T) {
+		_, err := remote.PeerFilter()
+		require.ErrorIs(t, err, ErrNotSupportedRemotely)
+	})
+	t.Run("DiscoveredPeers", func(t *testing.T) {
+		_, err := remote.DiscoveredPeers()
+		require.ErrorIs(t, err, ErrNotSupportedRemotely)
+	})
+	t.Run("InMemoryKeyring", func(t *testing.T) {
+		_, ok := remote.InMemoryKeyring()
+		require.False(t, ok)
+	})
+}
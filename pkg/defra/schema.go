@@ -6,6 +6,8 @@ import (
 	"os"
 
 	"github.com/shinzonetwork/app-sdk/pkg/file"
+	"github.com/shinzonetwork/app-sdk/pkg/sdl"
+	"github.com/sourcenetwork/defradb/client"
 	"github.com/sourcenetwork/defradb/node"
 )
 
@@ -40,8 +42,7 @@ func (schema *SchemaApplierFromFile) ApplySchema(ctx context.Context, defraNode
 		return fmt.Errorf("Failed to read schema file: %v", err)
 	}
 
-	_, err = defraNode.DB.AddSchema(ctx, string(schemaBytes))
-	return err
+	return addSchema(ctx, defraNode, string(schemaBytes))
 }
 
 type SchemaApplierFromProvidedSchema struct {
@@ -55,6 +56,24 @@ func NewSchemaApplierFromProvidedSchema(schema string) *SchemaApplierFromProvide
 }
 
 func (schema *SchemaApplierFromProvidedSchema) ApplySchema(ctx context.Context, defraNode *node.Node) error {
-	_, err := defraNode.DB.AddSchema(ctx, string(schema.ProvidedSchema))
+	return addSchema(ctx, defraNode, schema.ProvidedSchema)
+}
+
+// addSchema validates a schema before applying it, so that mistakes are reported with their line and column. Types
+// may refer to the collections already on the node.
+func addSchema(ctx context.Context, defraNode *node.Node, schema string) error {
+	collections, err := defraNode.DB.GetCollections(ctx, client.CollectionFetchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list existing collections: %w", err)
+	}
+	knownTypes := make([]string, 0, len(collections))
+	for _, collection := range collections {
+		knownTypes = append(knownTypes, collection.Name())
+	}
+	if _, err := sdl.ParseAndValidate(schema, knownTypes...); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	_, err = defraNode.DB.AddSchema(ctx, schema)
 	return err
 }
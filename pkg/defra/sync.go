@@ -0,0 +1,111 @@
+package defra
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sourcenetwork/defradb/event"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// WaitForDoc blocks until the document with the given docID is present on defraNode, whether written locally or
+// synced from a peer, or until ctx is done - use context.WithTimeout to bound the wait.
+// It listens on the node's event bus rather than polling, so it returns as soon as the document has been merged.
+func WaitForDoc(ctx context.Context, defraNode *node.Node, docID string) error {
+	query := `query($docID: ID) { _commits(docID: $docID) { cid } }`
+	variables := map[string]any{"docID": docID}
+	return waitForEvent(ctx, defraNode, fmt.Sprintf("doc %s", docID), query, variables, func(eventDocID string, _ string) bool {
+		return eventDocID == docID
+	})
+}
+
+// WaitForCommit blocks until the commit with the given CID is present on defraNode, or until ctx is done - use
+// context.WithTimeout to bound the wait. The CID may be any composite or field-level commit, e.g. a `_version` cid.
+// It listens on the node's event bus rather than polling, re-checking the node whenever a document is merged.
+func WaitForCommit(ctx context.Context, defraNode *node.Node, commitCID string) error {
+	query := `query($cid: ID) { _commits(cid: $cid) { cid } }`
+	variables := map[string]any{"cid": commitCID}
+	return waitForEvent(ctx, defraNode, fmt.Sprintf("commit %s", commitCID), query, variables, func(_ string, eventCID string) bool {
+		return eventCID == commitCID
+	})
+}
+
+// IsCommitNotFound reports whether a `_commits(cid: ...)` query failed because the node doesn't have the commit, e.g.
+// because it hasn't synced yet. DefraDB reports an unknown CID as an error rather than an empty result.
+func IsCommitNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "cid either does not exist")
+}
+
+// waitForEvent subscribes to document updates and merges, then returns once the given `_commits` query, run with
+// variables, finds something. matches reports whether an event is known to satisfy the wait without re-querying the node.
+func waitForEvent(ctx context.Context, defraNode *node.Node, waitingFor string, query string, variables map[string]any, matches func(docID string, cid string) bool) error {
+	if defraNode == nil || defraNode.DB == nil {
+		return fmt.Errorf("defra node cannot be nil")
+	}
+	// A remote DefraDB's event bus isn't reachable over HTTP
+	if IsRemote(defraNode) {
+		return fmt.Errorf("failed to wait for %s: %w", waitingFor, ErrNotSupportedRemotely)
+	}
+	bus := defraNode.DB.Events()
+	if bus == nil {
+		return fmt.Errorf("failed to wait for %s: node has no event bus", waitingFor)
+	}
+
+	// Subscribe before checking, so that nothing merged in between is missed
+	sub, err := bus.Subscribe(event.UpdateName, event.MergeCompleteName)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+	defer func() {
+		// The bus blocks while a subscriber's buffer is full, so keep draining until the unsubscribe closes it
+		go func() {
+			for range sub.Message() {
+			}
+		}()
+		bus.Unsubscribe(sub)
+	}()
+
+	present := func() (bool, error) {
+		commits, err := QueryArrayWithVariables[struct {
+			CID string `json:"cid"`
+		}](ctx, defraNode, query, variables)
+		if IsCommitNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to check for %s: %w", waitingFor, err)
+		}
+		return len(commits) > 0, nil
+	}
+	if found, err := present(); err != nil || found {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s: %w", waitingFor, ctx.Err())
+		case msg, ok := <-sub.Message():
+			if !ok {
+				return fmt.Errorf("event bus closed while waiting for %s", waitingFor)
+			}
+			var docID, commitCID string
+			switch data := msg.Data.(type) {
+			case event.Update:
+				docID, commitCID = data.DocID, data.Cid.String()
+			case event.MergeComplete:
+				docID, commitCID = data.Merge.DocID, data.Merge.Cid.String()
+			default:
+				continue
+			}
+			if matches(docID, commitCID) {
+				return nil
+			}
+			// A merge may bring in more commits than the head it names, so check the node itself
+			if found, err := present(); err != nil || found {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package defra
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func syncTestConfig() *config.Config {
+	testConfig := *DefaultConfig
+	testConfig.DefraDB.Url = "127.0.0.1:0"
+	testConfig.DefraDB.P2P.ListenAddr = "/ip4/127.0.0.1/tcp/0"
+	testConfig.DefraDB.Store.InMemory = true
+	return &testConfig
+}
+
+func TestWaitForDocAndCommit(t *testing.T) {
+	schema := NewSchemaApplierFromProvidedSchema(`type User { name: String }`)
+	writer, err := Start(t.Context(), syncTestConfig(), schema, "User")
+	require.NoError(t, err)
+	defer writer.Close(t.Context())
+	reader, err := Start(t.Context(), syncTestConfig(), schema, "User")
+	require.NoError(t, err)
+	defer reader.Close(t.Context())
+
+	writerPeerInfo, err := writer.DB.PeerInfo()
+	require.NoError(t, err)
+	require.NoError(t, reader.DB.Connect(t.Context(), writerPeerInfo))
+
+	type userWithVersion struct {
+		DocID   string `json:"_docID"`
+		Version []struct {
+			CID string `json:"cid"`
+		} `json:"_version"`
+	}
+	created, err := PostMutation[userWithVersion](t.Context(), writer, `mutation {
+		create_User(input: {name: "Quinn"}) {
+			_docID
+			_version {
+				cid
+			}
+		}
+	}`)
+	require.NoError(t, err)
+	require.NotEmpty(t, created.Version)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+	defer cancel()
+	// The commit isn't known to the reader until it syncs, which WaitForCommit must wait for rather than fail on
+	require.NoError(t, WaitForCommit(ctx, reader, created.Version[0].CID))
+	require.NoError(t, WaitForDoc(ctx, reader, created.DocID))
+
+	// Already present on the writer, so these return straight away
+	require.NoError(t, WaitForDoc(ctx, writer, created.DocID))
+	require.NoError(t, WaitForCommit(ctx, writer, created.Version[0].CID))
+
+	users, err := QueryArray[userWithVersion](t.Context(), reader, `query { User { _docID } }`)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+}
+
+func TestWaitForDocTimesOut(t *testing.T) {
+	schema := NewSchemaApplierFromProvidedSchema(`type User { name: String }`)
+	writer, err := Start(t.Context(), syncTestConfig(), schema, "User")
+	require.NoError(t, err)
+	defer writer.Close(t.Context())
+	unconnected, err := Start(t.Context(), syncTestConfig(), schema, "User")
+	require.NoError(t, err)
+	defer unconnected.Close(t.Context())
+
+	type userWithDocID struct {
+		DocID string `json:"_docID"`
+	}
+	created, err := PostMutation[userWithDocID](t.Context(), writer, `mutation { create_User(input: {name: "Quinn"}) { _docID } }`)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 500*time.Millisecond)
+	defer cancel()
+	err = WaitForDoc(ctx, unconnected, created.DocID)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWaitForDocPassesIDsAsVariables(t *testing.T) {
+	defraNode, err := Start(t.Context(), syncTestConfig(), NewSchemaApplierFromProvidedSchema(`type User { name: String }`), "User")
+	require.NoError(t, err)
+	defer defraNode.Close(t.Context())
+
+	// An ID that would break out of an inlined string is passed as a value, not parsed as part of the query
+	const malformed = `bad") { cid } }`
+	docCtx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+	err = WaitForDoc(docCtx, defraNode, malformed)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "Syntax Error")
+	commitCtx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+	err = WaitForCommit(commitCtx, defraNode, malformed)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "Syntax Error")
+}
@@ -0,0 +1,494 @@
+// Package defratest starts clusters of connected DefraDB nodes for tests, with helpers to wait for replication and to
+// inject faults such as stopped, restarted or partitioned nodes.
+package defratest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/shinzonetwork/app-sdk/pkg/sdl"
+	"github.com/sourcenetwork/defradb/node"
+)
+
+// Topology is the shape in which a Cluster's nodes are connected to each other
+type Topology string
+
+const (
+	// Star connects every node to node 0, which relays between them
+	Star Topology = "star"
+	// Mesh connects every node to every other node
+	Mesh Topology = "mesh"
+	// Chain connects each node to the next, so updates hop along the chain
+	Chain Topology = "chain"
+)
+
+const (
+	// DefaultPollInterval is how often WaitForSync checks whether replication has converged
+	DefaultPollInterval = 100 * time.Millisecond
+	// DefaultWaitTimeout bounds the Wait helpers when their context has no deadline
+	DefaultWaitTimeout = 30 * time.Second
+)
+
+// Option configures a Cluster
+type Option func(*options)
+
+type options struct {
+	topology    Topology
+	collections []string
+	inMemory    bool
+	configure   func(index int, cfg *config.Config)
+}
+
+// WithTopology sets how the nodes are connected. The default is Mesh.
+func WithTopology(topology Topology) Option {
+	return func(o *options) {
+		o.topology = topology
+	}
+}
+
+// WithCollections sets the collections every node subscribes to. The default is every type defined in the schema.
+func WithCollections(collections ...string) Option {
+	return func(o *options) {
+		o.collections = collections
+	}
+}
+
+// WithInMemoryStores keeps every node's store and identity in memory. Nodes start faster, but a stopped, restarted
+// or partitioned node comes back empty and with a new identity.
+func WithInMemoryStores() Option {
+	return func(o *options) {
+		o.inMemory = true
+	}
+}
+
+// WithConfig lets the caller adjust each node's config before the node first starts
+func WithConfig(configure func(index int, cfg *config.Config)) Option {
+	return func(o *options) {
+		o.configure = configure
+	}
+}
+
+type member struct {
+	cfg  *config.Config
+	node *node.Node
+	// isolated members are running but deliberately not connected to anyone
+	isolated bool
+}
+
+// Cluster is a set of DefraDB nodes sharing a schema, connected in a Topology. Nodes listen on loopback with random
+// ports, and are closed when the test finishes.
+type Cluster struct {
+	schema  string
+	options options
+
+	mu      sync.Mutex
+	members []*member
+}
+
+// NewCluster starts size nodes with the given schema, subscribes them to its collections and connects them
+// according to the topology. If any node fails to start, the nodes already started are closed.
+func NewCluster(t testing.TB, size int, schema string, opts ...Option) (*Cluster, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("cluster size must be at least 1, given: %d", size)
+	}
+	o := options{topology: Mesh}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	switch o.topology {
+	case Star, Mesh, Chain:
+	default:
+		return nil, fmt.Errorf("unknown topology %q", o.topology)
+	}
+	if o.collections == nil {
+		collections, err := schemaCollections(schema)
+		if err != nil {
+			return nil, err
+		}
+		o.collections = collections
+	}
+
+	cluster := &Cluster{schema: schema, options: o}
+	t.Cleanup(func() {
+		if err := cluster.Close(context.Background()); err != nil {
+			t.Logf("Error closing cluster: %v", err)
+		}
+	})
+
+	ctx := context.Background()
+	for i := 0; i < size; i++ {
+		cfg := &config.Config{
+			DefraDB: config.DefraDBConfig{
+				Url:           "127.0.0.1:0",
+				KeyringSecret: "testSecret",
+				P2P: config.DefraP2PConfig{
+					ListenAddr: "/ip4/127.0.0.1/tcp/0",
+				},
+				Store: config.DefraStoreConfig{
+					Path:     t.TempDir(),
+					InMemory: o.inMemory,
+				},
+			},
+		}
+		if o.configure != nil {
+			o.configure(i, cfg)
+		}
+		cluster.members = append(cluster.members, &member{cfg: cfg})
+		if err := cluster.start(ctx, i); err != nil {
+			cluster.Close(ctx)
+			return nil, err
+		}
+	}
+
+	for i := range cluster.members {
+		for _, j := range cluster.Neighbours(i) {
+			if j > i {
+				if err := cluster.Connect(ctx, i, j); err != nil {
+					cluster.Close(ctx)
+					return nil, err
+				}
+			}
+		}
+	}
+	return cluster, nil
+}
+
+// schemaCollections returns the name of every object type defined in the schema
+func schemaCollections(schema string) ([]string, error) {
+	parsed, err := sdl.Parse(schema)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing cluster schema: %w", err)
+	}
+	collections := []string{}
+	for _, definition := range parsed.Types {
+		if definition.Kind == sdl.KindObject {
+			collections = append(collections, definition.Name)
+		}
+	}
+	return collections, nil
+}
+
+// Size returns the number of nodes in the cluster, including stopped ones
+func (c *Cluster) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.members)
+}
+
+// Node returns the node at the given index, or nil if it is stopped
+func (c *Cluster) Node(index int) *node.Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index < 0 || index >= len(c.members) {
+		return nil
+	}
+	return c.members[index].node
+}
+
+// Nodes returns every node in index order, with nil in place of stopped nodes
+func (c *Cluster) Nodes() []*node.Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	nodes := make([]*node.Node, len(c.members))
+	for i, m := range c.members {
+		nodes[i] = m.node
+	}
+	return nodes
+}
+
+// Config returns the config the node at the given index was started with
+func (c *Cluster) Config(index int) *config.Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index < 0 || index >= len(c.members) {
+		return nil
+	}
+	return c.members[index].cfg
+}
+
+// Neighbours returns the indexes of the nodes the topology connects to the node at the given index
+func (c *Cluster) Neighbours(index int) []int {
+	size := c.Size()
+	neighbours := []int{}
+	for j := 0; j < size; j++ {
+		if j == index {
+			continue
+		}
+		switch c.options.topology {
+		case Star:
+			if index == 0 || j == 0 {
+				neighbours = append(neighbours, j)
+			}
+		case Mesh:
+			neighbours = append(neighbours, j)
+		case Chain:
+			if j == index-1 || j == index+1 {
+				neighbours = append(neighbours, j)
+			}
+		}
+	}
+	return neighbours
+}
+
+// Connect connects the nodes at the given indexes, whether or not the topology does
+func (c *Cluster) Connect(ctx context.Context, from int, to int) error {
+	fromNode, toNode := c.Node(from), c.Node(to)
+	if fromNode == nil || toNode == nil {
+		return fmt.Errorf("cannot connect node %d to node %d: both must be running", from, to)
+	}
+	peerInfo, err := toNode.DB.PeerInfo()
+	if err != nil {
+		return fmt.Errorf("failed to get peer info of node %d: %w", to, err)
+	}
+	if err := fromNode.DB.Connect(ctx, peerInfo); err != nil {
+		return fmt.Errorf("failed to connect node %d to node %d: %w", from, to, err)
+	}
+	return nil
+}
+
+func (c *Cluster) start(ctx context.Context, index int) error {
+	c.mu.Lock()
+	m := c.members[index]
+	c.mu.Unlock()
+
+	defraNode, err := defra.Start(ctx, m.cfg, defra.NewSchemaApplierFromProvidedSchema(c.schema), c.options.collections...)
+	if err != nil {
+		return fmt.Errorf("failed to start node %d: %w", index, err)
+	}
+
+	c.mu.Lock()
+	m.node = defraNode
+	c.mu.Unlock()
+	return nil
+}
+
+// Stop closes the node at the given index, keeping its store so that it can be restarted
+func (c *Cluster) Stop(ctx context.Context, index int) error {
+	c.mu.Lock()
+	if index < 0 || index >= len(c.members) {
+		c.mu.Unlock()
+		return fmt.Errorf("no node at index %d", index)
+	}
+	m := c.members[index]
+	defraNode := m.node
+	m.node = nil
+	c.mu.Unlock()
+
+	if defraNode == nil {
+		return nil
+	}
+	if err := defraNode.Close(ctx); err != nil {
+		return fmt.Errorf("failed to stop node %d: %w", index, err)
+	}
+	return nil
+}
+
+// Restart stops the node at the given index if it is running, starts it again from its store and reconnects it to
+// its running neighbours. The node comes back on new ports, with the same identity unless stores are in memory.
+func (c *Cluster) Restart(ctx context.Context, index int) error {
+	if err := c.Stop(ctx, index); err != nil {
+		return err
+	}
+	if err := c.start(ctx, index); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.members[index].isolated = false
+	c.mu.Unlock()
+	return c.connectToNeighbours(ctx, index)
+}
+
+// Partition cuts the node at the given index off from the rest of the cluster by restarting it on new ports without
+// connecting it to anyone. It keeps running and can be written to and queried; Heal reconnects it.
+func (c *Cluster) Partition(ctx context.Context, index int) error {
+	if err := c.Stop(ctx, index); err != nil {
+		return err
+	}
+	if err := c.start(ctx, index); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.members[index].isolated = true
+	c.mu.Unlock()
+	return nil
+}
+
+// Heal reconnects a partitioned node to its running neighbours. Updates made on either side of the partition are
+// exchanged as documents are next updated, or can be pulled with the node's DB.SyncDocuments.
+func (c *Cluster) Heal(ctx context.Context, index int) error {
+	c.mu.Lock()
+	if index < 0 || index >= len(c.members) {
+		c.mu.Unlock()
+		return fmt.Errorf("no node at index %d", index)
+	}
+	c.members[index].isolated = false
+	c.mu.Unlock()
+	return c.connectToNeighbours(ctx, index)
+}
+
+func (c *Cluster) connectToNeighbours(ctx context.Context, index int) error {
+	for _, j := range c.Neighbours(index) {
+		c.mu.Lock()
+		reachable := c.members[j].node != nil && !c.members[j].isolated
+		c.mu.Unlock()
+		if !reachable {
+			continue
+		}
+		if err := c.Connect(ctx, index, j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every running node. It is called automatically when the test finishes.
+func (c *Cluster) Close(ctx context.Context) error {
+	var errs []string
+	for i := 0; i < c.Size(); i++ {
+		if err := c.Stop(ctx, i); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close cluster: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// WaitForDoc waits until the node at the given index has the document with the given docID (see defra.WaitForDoc).
+// If ctx has no deadline, it gives up after DefaultWaitTimeout.
+func (c *Cluster) WaitForDoc(ctx context.Context, index int, docID string) error {
+	defraNode := c.Node(index)
+	if defraNode == nil {
+		return fmt.Errorf("node %d is stopped", index)
+	}
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	return defra.WaitForDoc(ctx, defraNode, docID)
+}
+
+// WaitForCommit waits until the node at the given index has the commit with the given CID (see defra.WaitForCommit).
+// If ctx has no deadline, it gives up after DefaultWaitTimeout.
+func (c *Cluster) WaitForCommit(ctx context.Context, index int, commitCID string) error {
+	defraNode := c.Node(index)
+	if defraNode == nil {
+		return fmt.Errorf("node %d is stopped", index)
+	}
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	return defra.WaitForCommit(ctx, defraNode, commitCID)
+}
+
+// WaitForSync waits until every running, unpartitioned node holds the same documents at the same versions in each
+// of the given collections - by default, every collection the cluster subscribes to.
+// If ctx has no deadline, it gives up after DefaultWaitTimeout.
+func (c *Cluster) WaitForSync(ctx context.Context, collections ...string) error {
+	if len(collections) == 0 {
+		collections = c.options.collections
+	}
+	return poll(ctx, "cluster to sync", func() (bool, error) {
+		for _, collection := range collections {
+			converged, err := c.converged(ctx, collection)
+			if err != nil || !converged {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+type docVersions struct {
+	DocID   string `json:"_docID"`
+	Version []struct {
+		CID string `json:"cid"`
+	} `json:"_version"`
+}
+
+// converged reports whether every running, unpartitioned node has the same heads for every doc in the collection
+func (c *Cluster) converged(ctx context.Context, collection string) (bool, error) {
+	query := fmt.Sprintf(`query { %s { _docID _version { cid } } }`, collection)
+
+	c.mu.Lock()
+	nodes := []*node.Node{}
+	for _, m := range c.members {
+		if m.node != nil && !m.isolated {
+			nodes = append(nodes, m.node)
+		}
+	}
+	c.mu.Unlock()
+
+	var expected string
+	for i, defraNode := range nodes {
+		docs, err := defra.QueryArray[docVersions](ctx, defraNode, query)
+		if err != nil {
+			return false, err
+		}
+		state := collectionState(docs)
+		if i == 0 {
+			expected = state
+		} else if state != expected {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// collectionState summarises a collection's docs and their version CIDs as a string that is equal across nodes
+// exactly when they hold the same versions
+func collectionState(docs []docVersions) string {
+	entries := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		cids := make([]string, 0, len(doc.Version))
+		for _, version := range doc.Version {
+			cids = append(cids, version.CID)
+		}
+		sort.Strings(cids)
+		entries = append(entries, doc.DocID+"="+strings.Join(cids, ","))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ";")
+}
+
+// poll calls check every DefaultPollInterval until it reports true, or ctx is done. Errors from check are treated as
+// "not yet" - e.g. a collection that hasn't synced - and the last one is reported on timeout.
+func poll(ctx context.Context, waitingFor string, check func() (bool, error)) error {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	ticker := time.NewTicker(DefaultPollInterval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		done, err := check()
+		if done {
+			return nil
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("timed out waiting for %s: %w (last error: %v)", waitingFor, ctx.Err(), lastErr)
+			}
+			return fmt.Errorf("timed out waiting for %s: %w", waitingFor, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// withDefaultTimeout bounds ctx by DefaultWaitTimeout, unless it already has a deadline
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, hasDeadline := ctx.Deadline(); hasDeadline {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, DefaultWaitTimeout)
+}
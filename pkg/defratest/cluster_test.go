@@ -0,0 +1,116 @@
+package defratest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/sourcenetwork/defradb/node"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `type User { name: String }`
+
+type user struct {
+	DocID string `json:"_docID"`
+	Name  string `json:"name"`
+}
+
+func createUser(t *testing.T, defraNode *node.Node, name string) string {
+	created, err := defra.PostMutation[user](t.Context(), defraNode, fmt.Sprintf(`mutation {
+		create_User(input: {name: %q}) {
+			_docID
+			name
+		}
+	}`, name))
+	require.NoError(t, err)
+	return created.DocID
+}
+
+func countUsers(t *testing.T, defraNode *node.Node, docID string) int {
+	users, err := defra.QueryArray[user](t.Context(), defraNode, fmt.Sprintf(`query { User(filter: {_docID: {_eq: %q}}) { _docID name } }`, docID))
+	require.NoError(t, err)
+	return len(users)
+}
+
+func TestNeighbours(t *testing.T) {
+	cluster := &Cluster{members: make([]*member, 4)}
+
+	cluster.options.topology = Star
+	require.Equal(t, []int{1, 2, 3}, cluster.Neighbours(0))
+	require.Equal(t, []int{0}, cluster.Neighbours(2))
+
+	cluster.options.topology = Mesh
+	require.Equal(t, []int{0, 1, 3}, cluster.Neighbours(2))
+
+	cluster.options.topology = Chain
+	require.Equal(t, []int{1}, cluster.Neighbours(0))
+	require.Equal(t, []int{1, 3}, cluster.Neighbours(2))
+	require.Equal(t, []int{2}, cluster.Neighbours(3))
+}
+
+func TestNewClusterRejectsInvalidInput(t *testing.T) {
+	_, err := NewCluster(t, 0, testSchema)
+	require.Error(t, err)
+
+	_, err = NewCluster(t, 2, testSchema, WithTopology("ring"))
+	require.Error(t, err)
+
+	_, err = NewCluster(t, 2, "type User {")
+	require.Error(t, err)
+}
+
+func TestClusterReplicatesAcrossTopologies(t *testing.T) {
+	for _, topology := range []Topology{Star, Mesh, Chain} {
+		t.Run(string(topology), func(t *testing.T) {
+			cluster, err := NewCluster(t, 3, testSchema, WithTopology(topology), WithInMemoryStores())
+			require.NoError(t, err)
+
+			// Write at one end; in a chain the update has to hop through the middle node
+			docID := createUser(t, cluster.Node(2), "Quinn")
+			require.NoError(t, cluster.WaitForDoc(t.Context(), 0, docID))
+			require.NoError(t, cluster.WaitForSync(t.Context()))
+			for _, defraNode := range cluster.Nodes() {
+				require.Equal(t, 1, countUsers(t, defraNode, docID))
+			}
+		})
+	}
+}
+
+func TestClusterStopAndRestart(t *testing.T) {
+	cluster, err := NewCluster(t, 3, testSchema)
+	require.NoError(t, err)
+
+	docID := createUser(t, cluster.Node(0), "Quinn")
+	require.NoError(t, cluster.WaitForSync(t.Context()))
+
+	require.NoError(t, cluster.Stop(t.Context(), 1))
+	require.Nil(t, cluster.Node(1))
+	require.Error(t, cluster.WaitForDoc(t.Context(), 1, docID))
+
+	require.NoError(t, cluster.Restart(t.Context(), 1))
+	// The restarted node kept its data, and is reconnected to the others
+	require.Equal(t, 1, countUsers(t, cluster.Node(1), docID))
+	laterDocID := createUser(t, cluster.Node(0), "Rowan")
+	require.NoError(t, cluster.WaitForDoc(t.Context(), 1, laterDocID))
+}
+
+func TestClusterPartitionAndHeal(t *testing.T) {
+	cluster, err := NewCluster(t, 3, testSchema)
+	require.NoError(t, err)
+
+	require.NoError(t, cluster.Partition(t.Context(), 2))
+	docID := createUser(t, cluster.Node(0), "Quinn")
+	require.NoError(t, cluster.WaitForDoc(t.Context(), 1, docID))
+	require.NoError(t, cluster.WaitForSync(t.Context())) // Only the connected nodes need to agree
+
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+	require.Error(t, cluster.WaitForDoc(ctx, 2, docID), "a partitioned node should not receive updates")
+
+	require.NoError(t, cluster.Heal(t.Context(), 2))
+	laterDocID := createUser(t, cluster.Node(0), "Rowan")
+	require.NoError(t, cluster.WaitForDoc(t.Context(), 2, laterDocID))
+}
@@ -7,10 +7,11 @@ import (
 // Error code constants for monitoring and metrics
 const (
 	// Network error codes
-	CodeRPCTimeout          = "RPC_TIMEOUT"
-	CodeRPCConnectionFailed = "RPC_CONNECTION_FAILED"
-	CodeHTTPError           = "HTTP_ERROR"
-	CodeRateLimited         = "RATE_LIMITED"
+	CodeRPCTimeout           = "RPC_TIMEOUT"
+	CodeRPCConnectionFailed  = "RPC_CONNECTION_FAILED"
+	CodeHTTPError            = "HTTP_ERROR"
+	CodeRateLimited          = "RATE_LIMITED"
+	CodePeerConnectionFailed = "PEER_CONNECTION_FAILED"
 
 	// Data error codes
 	CodeInvalidHex           = "INVALID_HEX"
@@ -65,6 +66,14 @@ func NewRateLimited(component, operation string, input_data string, underlying e
 	}
 }
 
+// NewPeerConnectionFailed creates an error for failed connections to P2P peers, retried with backoff
+func NewPeerConnectionFailed(component, operation string, input_data string, underlying error, ctx ...ContextOption) IndexerError {
+	return &NetworkError{
+		baseError: newBaseError(CodePeerConnectionFailed, "Failed to connect to peer", Warning, RetryableWithBackoff,
+			component, operation, input_data, underlying, ctx...),
+	}
+}
+
 // DataError constructors
 
 // NewInvalidHex creates an error for invalid hexadecimal string inputs
@@ -5,14 +5,133 @@ import (
 	"net"
 )
 
+// Preferences controls which local address GetLANIPWithPreferences picks. The zero value picks the first
+// non-loopback address, preferring IPv4 and falling back to loopback if the machine has no other address.
+type Preferences struct {
+	// Interface restricts the search to the named interface, e.g. "eth0"
+	Interface string
+	// CIDR restricts the search to addresses within the given network, e.g. "192.168.0.0/16"
+	CIDR string
+	// IPVersion restricts the search to IPv4 (4) or IPv6 (6) addresses; 0 allows either, preferring IPv4
+	IPVersion int
+	// LoopbackOnly picks a loopback address, for nodes that must not be reachable from other machines
+	LoopbackOnly bool
+}
+
+// interfaceAddrs is the subset of a network interface that address selection looks at
+type interfaceAddrs struct {
+	name  string
+	up    bool
+	addrs []net.IP
+}
+
+// GetLANIP returns this machine's LAN address, chosen from its network interfaces with the default Preferences.
+// It never needs outbound connectivity.
 func GetLANIP() (string, error) {
-	conn, err := net.Dial("udp", "8.8.8.8:80")
+	return GetLANIPWithPreferences(Preferences{})
+}
+
+// GetLANIPWithPreferences returns a local address chosen from this machine's network interfaces according to prefs.
+// It never needs outbound connectivity. If no interface or CIDR is specified and the machine has no usable
+// non-loopback address (e.g. an air-gapped machine), the loopback address is returned.
+func GetLANIPWithPreferences(prefs Preferences) (string, error) {
+	interfaces, err := listInterfaces()
 	if err != nil {
-		return "", fmt.Errorf("Error retrieving ip address: %v", err)
+		return "", err
+	}
+	return selectAddress(interfaces, prefs)
+}
+
+// IsIPv6 reports whether address is an IPv6 address, e.g. to choose between /ip4 and /ip6 multiaddrs
+func IsIPv6(address string) bool {
+	ip := net.ParseIP(address)
+	return ip != nil && ip.To4() == nil
+}
+
+func listInterfaces() ([]interfaceAddrs, error) {
+	netInterfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing network interfaces: %v", err)
+	}
+
+	interfaces := make([]interfaceAddrs, 0, len(netInterfaces))
+	for _, netInterface := range netInterfaces {
+		addrs, err := netInterface.Addrs()
+		if err != nil {
+			continue // An interface we can't read can't be picked; the others may still do
+		}
+		entry := interfaceAddrs{name: netInterface.Name, up: netInterface.Flags&net.FlagUp != 0}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				entry.addrs = append(entry.addrs, ipNet.IP)
+			}
+		}
+		interfaces = append(interfaces, entry)
 	}
-	defer conn.Close()
+	return interfaces, nil
+}
 
-	localAddr := conn.LocalAddr().(*net.UDPAddr)
+func selectAddress(interfaces []interfaceAddrs, prefs Preferences) (string, error) {
+	if prefs.IPVersion != 0 && prefs.IPVersion != 4 && prefs.IPVersion != 6 {
+		return "", fmt.Errorf("Invalid IP version %d, expected 4, 6 or 0 for either", prefs.IPVersion)
+	}
+	var network *net.IPNet
+	if prefs.CIDR != "" {
+		var err error
+		_, network, err = net.ParseCIDR(prefs.CIDR)
+		if err != nil {
+			return "", fmt.Errorf("Invalid CIDR %s: %v", prefs.CIDR, err)
+		}
+	}
+
+	foundInterface := prefs.Interface == ""
+	var ipv4, ipv6 []net.IP
+	for _, candidate := range interfaces {
+		if prefs.Interface != "" && candidate.name != prefs.Interface {
+			continue
+		}
+		foundInterface = true
+		if !candidate.up {
+			continue
+		}
+		for _, ip := range candidate.addrs {
+			if ip.IsLoopback() != prefs.LoopbackOnly || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				continue
+			}
+			if network != nil && !network.Contains(ip) {
+				continue
+			}
+			if ip.To4() != nil {
+				ipv4 = append(ipv4, ip)
+			} else {
+				ipv6 = append(ipv6, ip)
+			}
+		}
+	}
+	if !foundInterface {
+		return "", fmt.Errorf("Network interface %s not found", prefs.Interface)
+	}
 
-	return localAddr.IP.String(), nil
+	switch {
+	case prefs.IPVersion != 6 && len(ipv4) > 0:
+		return ipv4[0].String(), nil
+	case prefs.IPVersion != 4 && len(ipv6) > 0:
+		return ipv6[0].String(), nil
+	}
+
+	// Without an explicit interface or network to honour, fall back to loopback so that a node can still start offline
+	if prefs.Interface == "" && network == nil {
+		if prefs.IPVersion == 6 {
+			return net.IPv6loopback.String(), nil
+		}
+		return "127.0.0.1", nil
+	}
+	return "", fmt.Errorf("No IPv%s address found matching interface %q and CIDR %q", ipVersionName(prefs.IPVersion), prefs.Interface, prefs.CIDR)
+}
+
+func ipVersionName(version int) string {
+	if version == 0 {
+		return "4/IPv6"
+	}
+	return fmt.Sprint(version)
 }
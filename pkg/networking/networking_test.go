@@ -0,0 +1,80 @@
+package networking
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testInterfaces() []interfaceAddrs {
+	return []interfaceAddrs{
+		{name: "lo", up: true, addrs: []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}},
+		{name: "docker0", up: false, addrs: []net.IP{net.ParseIP("172.17.0.1")}},
+		{name: "eth0", up: true, addrs: []net.IP{net.ParseIP("fe80::1"), net.ParseIP("2001:db8::10"), net.ParseIP("10.0.0.5")}},
+		{name: "wlan0", up: true, addrs: []net.IP{net.ParseIP("192.168.1.20")}},
+	}
+}
+
+func TestSelectAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefs    Preferences
+		expected string
+	}{
+		{"defaults prefer IPv4 on the first usable interface", Preferences{}, "10.0.0.5"},
+		{"interface", Preferences{Interface: "wlan0"}, "192.168.1.20"},
+		{"CIDR", Preferences{CIDR: "192.168.0.0/16"}, "192.168.1.20"},
+		{"IPv6 skips link-local addresses", Preferences{IPVersion: 6}, "2001:db8::10"},
+		{"loopback only", Preferences{LoopbackOnly: true}, "127.0.0.1"},
+		{"loopback only IPv6", Preferences{LoopbackOnly: true, IPVersion: 6}, "::1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			address, err := selectAddress(testInterfaces(), test.prefs)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, address)
+		})
+	}
+}
+
+func TestSelectAddressFallsBackToLoopbackOffline(t *testing.T) {
+	offline := []interfaceAddrs{{name: "lo", up: true, addrs: []net.IP{net.ParseIP("127.0.0.1")}}}
+
+	address, err := selectAddress(offline, Preferences{})
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1", address)
+
+	address, err = selectAddress(nil, Preferences{IPVersion: 6})
+	require.NoError(t, err)
+	require.Equal(t, "::1", address)
+}
+
+func TestSelectAddressHonoursExplicitPreferences(t *testing.T) {
+	_, err := selectAddress(testInterfaces(), Preferences{Interface: "eth1"})
+	require.Error(t, err)
+
+	_, err = selectAddress(testInterfaces(), Preferences{Interface: "docker0"}) // Down
+	require.Error(t, err)
+
+	_, err = selectAddress(testInterfaces(), Preferences{CIDR: "172.16.0.0/12"})
+	require.Error(t, err)
+
+	_, err = selectAddress(testInterfaces(), Preferences{CIDR: "not a cidr"})
+	require.Error(t, err)
+
+	_, err = selectAddress(testInterfaces(), Preferences{IPVersion: 5})
+	require.Error(t, err)
+}
+
+func TestGetLANIP(t *testing.T) {
+	address, err := GetLANIP()
+	require.NoError(t, err)
+	require.NotNil(t, net.ParseIP(address))
+}
+
+func TestIsIPv6(t *testing.T) {
+	require.False(t, IsIPv6("10.0.0.5"))
+	require.True(t, IsIPv6("2001:db8::10"))
+	require.False(t, IsIPv6("localhost"))
+}
@@ -0,0 +1,246 @@
+package sdl
+
+import (
+	"fmt"
+
+	"github.com/sourcenetwork/graphql-go/language/ast"
+	"github.com/sourcenetwork/graphql-go/language/location"
+	"github.com/sourcenetwork/graphql-go/language/parser"
+	"github.com/sourcenetwork/graphql-go/language/printer"
+	"github.com/sourcenetwork/graphql-go/language/source"
+)
+
+// Parse parses an SDL document into a Schema, using the GraphQL parser DefraDB itself uses.
+// Errors report the line and column at which parsing failed.
+func Parse(sdl string) (*Schema, error) {
+	src := source.NewSource(&source.Source{Body: []byte(sdl)})
+	document, err := parser.Parse(parser.ParseParams{Source: src})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &converter{source: src}
+	schema := &Schema{
+		Types:          []*TypeDefinition{},
+		Extensions:     []*TypeDefinition{},
+		Directives:     []*DirectiveDefinition{},
+		RootOperations: map[string]string{},
+	}
+	for _, node := range document.Definitions {
+		switch definition := node.(type) {
+		case *ast.ObjectDefinition:
+			schema.Types = append(schema.Types, c.objectDefinition(definition))
+		case *ast.InterfaceDefinition:
+			schema.Types = append(schema.Types, &TypeDefinition{
+				Kind:        KindInterface,
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+				Directives:  directives(definition.Directives),
+				Fields:      c.fields(definition.Fields),
+				Position:    c.position(definition.Loc),
+			})
+		case *ast.InputObjectDefinition:
+			schema.Types = append(schema.Types, &TypeDefinition{
+				Kind:        KindInput,
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+				Directives:  directives(definition.Directives),
+				Fields:      c.inputFields(definition.Fields),
+				Position:    c.position(definition.Loc),
+			})
+		case *ast.EnumDefinition:
+			values := []string{}
+			for _, value := range definition.Values {
+				values = append(values, value.Name.Value)
+			}
+			schema.Types = append(schema.Types, &TypeDefinition{
+				Kind:        KindEnum,
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+				Directives:  directives(definition.Directives),
+				EnumValues:  values,
+				Position:    c.position(definition.Loc),
+			})
+		case *ast.UnionDefinition:
+			schema.Types = append(schema.Types, &TypeDefinition{
+				Kind:         KindUnion,
+				Name:         definition.Name.Value,
+				Description:  description(definition.Description),
+				Directives:   directives(definition.Directives),
+				UnionMembers: names(definition.Types),
+				Position:     c.position(definition.Loc),
+			})
+		case *ast.ScalarDefinition:
+			schema.Types = append(schema.Types, &TypeDefinition{
+				Kind:        KindScalar,
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+				Directives:  directives(definition.Directives),
+				Position:    c.position(definition.Loc),
+			})
+		case *ast.TypeExtensionDefinition:
+			extension := c.objectDefinition(definition.Definition)
+			extension.Extension = true
+			extension.Position = c.position(definition.Loc)
+			schema.Extensions = append(schema.Extensions, extension)
+		case *ast.DirectiveDefinition:
+			locations := []string{}
+			for _, name := range definition.Locations {
+				locations = append(locations, name.Value)
+			}
+			schema.Directives = append(schema.Directives, &DirectiveDefinition{
+				Name:      definition.Name.Value,
+				Arguments: c.inputValues(definition.Arguments),
+				Locations: locations,
+			})
+		case *ast.SchemaDefinition:
+			for _, operation := range definition.OperationTypes {
+				schema.RootOperations[operation.Operation] = operation.Type.Name.Value
+			}
+		default:
+			return nil, fmt.Errorf("%s: unexpected %s in schema definition language", c.position(node.GetLoc()), node.GetKind())
+		}
+	}
+
+	schema.mergeExtensions()
+	return schema, nil
+}
+
+// converter turns the GraphQL parser's AST into the schema model, resolving positions against the source
+type converter struct {
+	source *source.Source
+}
+
+func (c *converter) position(loc *ast.Location) Position {
+	if loc == nil {
+		return Position{}
+	}
+	sourceLocation := location.GetLocation(c.source, loc.Start)
+	return Position{Line: sourceLocation.Line, Column: sourceLocation.Column}
+}
+
+func (c *converter) objectDefinition(definition *ast.ObjectDefinition) *TypeDefinition {
+	return &TypeDefinition{
+		Kind:        KindObject,
+		Name:        definition.Name.Value,
+		Description: description(definition.Description),
+		Interfaces:  names(definition.Interfaces),
+		Directives:  directives(definition.Directives),
+		Fields:      c.fields(definition.Fields),
+		Position:    c.position(definition.Loc),
+	}
+}
+
+func (c *converter) fields(definitions []*ast.FieldDefinition) []*Field {
+	fields := []*Field{}
+	for _, definition := range definitions {
+		fields = append(fields, &Field{
+			Name:        definition.Name.Value,
+			Description: description(definition.Description),
+			Type:        typeRef(definition.Type),
+			Arguments:   c.inputValues(definition.Arguments),
+			Directives:  directives(definition.Directives),
+			Position:    c.position(definition.Loc),
+		})
+	}
+	return fields
+}
+
+func (c *converter) inputFields(definitions []*ast.InputValueDefinition) []*Field {
+	fields := []*Field{}
+	for _, definition := range definitions {
+		fields = append(fields, &Field{
+			Name:         definition.Name.Value,
+			Description:  description(definition.Description),
+			Type:         typeRef(definition.Type),
+			Directives:   directives(definition.Directives),
+			DefaultValue: literal(definition.DefaultValue),
+			Position:     c.position(definition.Loc),
+		})
+	}
+	return fields
+}
+
+func (c *converter) inputValues(definitions []*ast.InputValueDefinition) []*InputValue {
+	values := []*InputValue{}
+	for _, definition := range definitions {
+		values = append(values, &InputValue{
+			Name:         definition.Name.Value,
+			Description:  description(definition.Description),
+			Type:         typeRef(definition.Type),
+			DefaultValue: literal(definition.DefaultValue),
+			Directives:   directives(definition.Directives),
+		})
+	}
+	return values
+}
+
+func typeRef(t ast.Type) TypeRef {
+	switch t := t.(type) {
+	case *ast.NonNull:
+		ref := typeRef(t.Type)
+		ref.NonNull = true
+		return ref
+	case *ast.List:
+		elem := typeRef(t.Type)
+		return TypeRef{Elem: &elem}
+	case *ast.Named:
+		return TypeRef{Name: t.Name.Value}
+	default:
+		return TypeRef{}
+	}
+}
+
+func directives(applied []*ast.Directive) []Directive {
+	result := []Directive{}
+	for _, directive := range applied {
+		arguments := []Argument{}
+		for _, argument := range directive.Arguments {
+			arguments = append(arguments, Argument{Name: argument.Name.Value, Value: literal(argument.Value)})
+		}
+		result = append(result, Directive{Name: directive.Name.Value, Arguments: arguments})
+	}
+	return result
+}
+
+// literal prints a value back to its GraphQL literal, e.g. `"abc"` or `[1, 2]`; it's empty if there's no value
+func literal(value ast.Value) string {
+	if value == nil {
+		return ""
+	}
+	printed, _ := printer.Print(value).(string)
+	return printed
+}
+
+func description(value *ast.StringValue) string {
+	if value == nil {
+		return ""
+	}
+	return value.Value
+}
+
+func names(named []*ast.Named) []string {
+	result := []string{}
+	for _, n := range named {
+		result = append(result, n.Name.Value)
+	}
+	return result
+}
+
+// mergeExtensions folds `extend` definitions into the types they extend, leaving only extensions of types defined elsewhere
+func (s *Schema) mergeExtensions() {
+	unresolved := []*TypeDefinition{}
+	for _, extension := range s.Extensions {
+		base := s.Type(extension.Name)
+		if base == nil || base.Kind != extension.Kind {
+			unresolved = append(unresolved, extension)
+			continue
+		}
+		base.Interfaces = append(base.Interfaces, extension.Interfaces...)
+		base.Directives = append(base.Directives, extension.Directives...)
+		base.Fields = append(base.Fields, extension.Fields...)
+		base.EnumValues = append(base.EnumValues, extension.EnumValues...)
+		base.UnionMembers = append(base.UnionMembers, extension.UnionMembers...)
+	}
+	s.Extensions = unresolved
+}
@@ -0,0 +1,159 @@
+package sdl
+
+import (
+	"testing"
+
+	"github.com/shinzonetwork/indexer/pkg/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIndexerSchema(t *testing.T) {
+	parsed, err := Parse(schema.GetSchema())
+	require.NoError(t, err)
+	require.NoError(t, parsed.Validate())
+
+	require.ElementsMatch(t, []string{"Block", "Transaction", "AccessListEntry", "Log"}, parsed.TypeNames(KindObject))
+
+	block := parsed.Type("Block")
+	require.NotNil(t, block)
+	_, branchable := block.Directive("branchable")
+	require.True(t, branchable)
+
+	hash := block.Field("hash")
+	require.NotNil(t, hash)
+	require.Equal(t, "String", hash.Type.String())
+	index, ok := hash.Directive("index")
+	require.True(t, ok)
+	unique, ok := index.Argument("unique")
+	require.True(t, ok)
+	require.Equal(t, "true", unique)
+
+	transactions := block.Field("transactions")
+	require.NotNil(t, transactions)
+	require.True(t, transactions.Type.IsList())
+	require.Equal(t, "Transaction", transactions.Type.NamedType())
+
+	require.Contains(t, parsed.Relations(), Relation{Type: "Block", Field: "transactions", Target: "Transaction", List: true, Name: "block_transactions"})
+	require.Contains(t, parsed.Relations(), Relation{Type: "Log", Field: "block", Target: "Block", Name: "block_transactions"})
+}
+
+func TestParseHandlesWhatRegexesDoNot(t *testing.T) {
+	source := `
+		# type Commented { out: String }
+		"""
+		A described type
+		"""
+		type Named implements Node & Other @policy(id: "abc", resource: {name: "users", groups: ["a", "b"]}) {
+			"the id"
+			id: ID!
+			tags(first: Int = 10): [String!]! @default(value: "{ not a type }")
+		}
+
+		interface Node { id: ID! }
+		interface Other { id: ID! }
+
+		extend type Named {
+			extra: Float
+		}
+
+		extend type Elsewhere {
+			more: String
+		}
+
+		enum Color { RED GREEN @deprecated BLUE }
+		union Thing = Named | Color
+		input Filter { name: String = "x", limit: Int }
+		scalar Address
+		directive @policy(id: String, resource: JSON) on OBJECT | FIELD_DEFINITION
+		schema { query: Named }
+	`
+
+	parsed, err := Parse(source)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"Named", "Node", "Other", "Color", "Thing", "Filter", "Address"}, parsed.TypeNames())
+	require.False(t, parsed.HasType("Commented"))
+
+	named := parsed.Type("Named")
+	require.Equal(t, KindObject, named.Kind)
+	require.Equal(t, "A described type", named.Description)
+	require.Equal(t, []string{"Node", "Other"}, named.Interfaces)
+	require.Len(t, named.Fields, 3) // Including the field from `extend type`
+	require.Equal(t, "the id", named.Field("id").Description)
+	require.Equal(t, "ID!", named.Field("id").Type.String())
+	require.Equal(t, "[String!]!", named.Field("tags").Type.String())
+	require.Equal(t, "10", named.Field("tags").Arguments[0].DefaultValue)
+
+	policy, ok := named.Directive("policy")
+	require.True(t, ok)
+	id, ok := policy.StringArgument("id")
+	require.True(t, ok)
+	require.Equal(t, "abc", id)
+	resource, _ := policy.Argument("resource")
+	require.Equal(t, `{name: "users", groups: ["a", "b"]}`, resource)
+
+	defaultDirective, ok := named.Field("tags").Directive("default")
+	require.True(t, ok)
+	value, _ := defaultDirective.StringArgument("value")
+	require.Equal(t, "{ not a type }", value)
+
+	require.Len(t, parsed.Extensions, 1)
+	require.Equal(t, "Elsewhere", parsed.Extensions[0].Name)
+
+	require.Equal(t, []string{"RED", "GREEN", "BLUE"}, parsed.Type("Color").EnumValues)
+	require.Equal(t, []string{"Named", "Color"}, parsed.Type("Thing").UnionMembers)
+	require.Equal(t, `"x"`, parsed.Type("Filter").Field("name").DefaultValue)
+	require.Len(t, parsed.Directives, 1)
+	require.Equal(t, []string{"OBJECT", "FIELD_DEFINITION"}, parsed.Directives[0].Locations)
+	require.Equal(t, "Named", parsed.RootOperations["query"])
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"missing closing brace", "type User { name: String"},
+		{"unknown definition", "table User { name: String }"},
+		{"unterminated string", `type User @index(name: "abc) { name: String }`},
+		{"unbalanced list type", "type User { names: [String }"},
+		{"unexpected character", "type User { name: String; }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			require.Error(t, err)
+		})
+	}
+
+	_, err := Parse("type User {\n  name: String\n  age Int\n}")
+	require.ErrorContains(t, err, "(3:7)")
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		knownTypes []string
+		wantErr    string
+	}{
+		{"valid", "type User { name: String friends: [User] }", nil, ""},
+		{"duplicate type", "type User { name: String } type User { age: Int }", nil, "defined more than once"},
+		{"duplicate field", "type User { name: String name: Int }", nil, "User.name is defined more than once"},
+		{"undefined type", "type User { block: Block }", nil, "undefined type Block"},
+		{"known external type", "type User { block: Block }", []string{"Block"}, ""},
+		{"no fields", "type User {}", nil, "has no fields"},
+		{"missing field type", "type User { name: }", nil, "User.name has no type"},
+		{"undefined interface", "type User implements Node { id: ID }", nil, "undefined interface Node"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAndValidate(tt.source, tt.knownTypes...)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+// Package sdl parses GraphQL Schema Definition Language into a small schema model, for inspecting the collections and
+// Views a schema describes. Parsing is done by DefraDB's own GraphQL parser, so any SDL DefraDB accepts parses here:
+// type, interface, input, enum, union and scalar definitions, `extend type`, directives, descriptions and comments.
+package sdl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Kind is the kind of a type definition, named after its SDL keyword
+type Kind string
+
+const (
+	KindObject    Kind = "type"
+	KindInterface Kind = "interface"
+	KindInput     Kind = "input"
+	KindEnum      Kind = "enum"
+	KindUnion     Kind = "union"
+	KindScalar    Kind = "scalar"
+)
+
+// BuiltinScalars are the scalar types understood by DefraDB without being defined in the schema
+var BuiltinScalars = []string{"Boolean", "Int", "Float", "Float32", "Float64", "String", "ID", "DateTime", "Blob", "JSON"}
+
+// Position is a 1-based line and column in the SDL source
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Schema is a parsed SDL document.
+type Schema struct {
+	// Types holds every type definition in source order, with any `extend` definitions for them merged in
+	Types []*TypeDefinition
+	// Extensions holds `extend` definitions for types that are not defined in this document
+	Extensions []*TypeDefinition
+	// Directives holds `directive @name on ...` definitions
+	Directives []*DirectiveDefinition
+	// RootOperations maps an operation (query, mutation, subscription) to its root type, from a `schema { ... }` definition
+	RootOperations map[string]string
+}
+
+// TypeDefinition is a type, interface, input, enum, union or scalar definition
+type TypeDefinition struct {
+	Kind        Kind
+	Name        string
+	Description string
+	Interfaces  []string
+	Directives  []Directive
+	// Fields holds the fields of a type or interface, and the input values of an input
+	Fields       []*Field
+	EnumValues   []string
+	UnionMembers []string
+	Extension    bool
+	Position     Position
+}
+
+// Field is a field of a type, interface or input
+type Field struct {
+	Name        string
+	Description string
+	Type        TypeRef
+	Arguments   []*InputValue
+	Directives  []Directive
+	// DefaultValue is only set for input fields
+	DefaultValue string
+	Position     Position
+}
+
+// InputValue is an argument of a field or directive definition
+type InputValue struct {
+	Name         string
+	Description  string
+	Type         TypeRef
+	DefaultValue string
+	Directives   []Directive
+}
+
+// TypeRef is a (possibly list and/or non-null) reference to a named type
+type TypeRef struct {
+	// Name is empty for list types
+	Name    string
+	Elem    *TypeRef
+	NonNull bool
+}
+
+// Directive is a directive applied to a definition, e.g. `@index(unique: true)`
+type Directive struct {
+	Name      string
+	Arguments []Argument
+}
+
+// Argument is a directive argument. Value holds the argument's GraphQL literal, e.g. `"block_transactions"`, `true` or `[1, 2]`
+type Argument struct {
+	Name  string
+	Value string
+}
+
+// DirectiveDefinition is a `directive @name(args) on LOCATIONS` definition
+type DirectiveDefinition struct {
+	Name      string
+	Arguments []*InputValue
+	Locations []string
+}
+
+// Relation is a field of an object type that refers to another object or interface type in the schema
+type Relation struct {
+	Type   string
+	Field  string
+	Target string
+	List   bool
+	// Name is the `@relation(name: ...)` of the field, if any
+	Name string
+}
+
+// Type returns the type definition with the given name, or nil if the schema does not define it
+func (s *Schema) Type(name string) *TypeDefinition {
+	for _, definition := range s.Types {
+		if definition.Name == name {
+			return definition
+		}
+	}
+	return nil
+}
+
+// HasType reports whether the schema defines a type of any kind with the given name
+func (s *Schema) HasType(name string) bool {
+	return s.Type(name) != nil
+}
+
+// TypeNames returns the names of the types of the given kinds (all kinds if none are given) in source order
+func (s *Schema) TypeNames(kinds ...Kind) []string {
+	names := []string{}
+	for _, definition := range s.Types {
+		if len(kinds) == 0 || containsKind(kinds, definition.Kind) {
+			names = append(names, definition.Name)
+		}
+	}
+	return names
+}
+
+// ObjectTypes returns the object (`type`) definitions in source order
+func (s *Schema) ObjectTypes() []*TypeDefinition {
+	types := []*TypeDefinition{}
+	for _, definition := range s.Types {
+		if definition.Kind == KindObject {
+			types = append(types, definition)
+		}
+	}
+	return types
+}
+
+// Relations returns every field of an object type whose type is another object or interface type in the schema
+func (s *Schema) Relations() []Relation {
+	relations := []Relation{}
+	for _, definition := range s.ObjectTypes() {
+		for _, field := range definition.Fields {
+			target := s.Type(field.Type.NamedType())
+			if target == nil || (target.Kind != KindObject && target.Kind != KindInterface) {
+				continue
+			}
+			relation := Relation{
+				Type:   definition.Name,
+				Field:  field.Name,
+				Target: target.Name,
+				List:   field.Type.IsList(),
+			}
+			if directive, ok := field.Directive("relation"); ok {
+				relation.Name, _ = directive.StringArgument("name")
+			}
+			relations = append(relations, relation)
+		}
+	}
+	return relations
+}
+
+// Field returns the field with the given name, or nil if the type has no such field
+func (t *TypeDefinition) Field(name string) *Field {
+	for _, field := range t.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// Directive returns the first directive with the given name applied to the type
+func (t *TypeDefinition) Directive(name string) (Directive, bool) {
+	return findDirective(t.Directives, name)
+}
+
+// Directive returns the first directive with the given name applied to the field
+func (f *Field) Directive(name string) (Directive, bool) {
+	return findDirective(f.Directives, name)
+}
+
+// Argument returns the GraphQL literal passed for the named argument
+func (d Directive) Argument(name string) (string, bool) {
+	for _, argument := range d.Arguments {
+		if argument.Name == name {
+			return argument.Value, true
+		}
+	}
+	return "", false
+}
+
+// StringArgument returns the named argument if it is a string literal, unquoted
+func (d Directive) StringArgument(name string) (string, bool) {
+	value, ok := d.Argument(name)
+	if !ok {
+		return "", false
+	}
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return "", false
+	}
+	return unquoted, true
+}
+
+// NamedType returns the name of the type at the core of any list and non-null wrappers
+func (t TypeRef) NamedType() string {
+	if t.Elem != nil {
+		return t.Elem.NamedType()
+	}
+	return t.Name
+}
+
+// IsList reports whether the type is a list
+func (t TypeRef) IsList() bool {
+	return t.Elem != nil
+}
+
+// String returns the type in SDL form, e.g. `[String!]!`
+func (t TypeRef) String() string {
+	result := t.Name
+	if t.Elem != nil {
+		result = "[" + t.Elem.String() + "]"
+	}
+	if t.NonNull {
+		result += "!"
+	}
+	return result
+}
+
+// IsBuiltinScalar reports whether name is one of the BuiltinScalars
+func IsBuiltinScalar(name string) bool {
+	for _, scalar := range BuiltinScalars {
+		if scalar == name {
+			return true
+		}
+	}
+	return false
+}
+
+func findDirective(directives []Directive, name string) (Directive, bool) {
+	for _, directive := range directives {
+		if directive.Name == name {
+			return directive, true
+		}
+	}
+	return Directive{}, false
+}
+
+func containsKind(kinds []Kind, kind Kind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package sdl
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate checks the schema for mistakes that DefraDB would otherwise only report when the schema is applied:
+// duplicate types or fields, types without fields, fields without types, and references to undefined types.
+// knownTypes lists types defined outside this document (e.g. collections already on the node) that may be referenced.
+func (s *Schema) Validate(knownTypes ...string) error {
+	defined := map[string]bool{}
+	for _, name := range BuiltinScalars {
+		defined[name] = true
+	}
+	for _, name := range knownTypes {
+		defined[name] = true
+	}
+
+	var errs []error
+	seen := map[string]bool{}
+	for _, definition := range s.Types {
+		if seen[definition.Name] {
+			errs = append(errs, fmt.Errorf("%s: type %s is defined more than once", definition.Position, definition.Name))
+		}
+		seen[definition.Name] = true
+		defined[definition.Name] = true
+	}
+
+	for _, definition := range append(append([]*TypeDefinition{}, s.Types...), s.Extensions...) {
+		switch definition.Kind {
+		case KindObject, KindInterface, KindInput:
+			if len(definition.Fields) == 0 && !definition.Extension {
+				errs = append(errs, fmt.Errorf("%s: %s %s has no fields", definition.Position, definition.Kind, definition.Name))
+			}
+		case KindEnum:
+			if len(definition.EnumValues) == 0 && !definition.Extension {
+				errs = append(errs, fmt.Errorf("%s: enum %s has no values", definition.Position, definition.Name))
+			}
+		case KindUnion:
+			if len(definition.UnionMembers) == 0 && !definition.Extension {
+				errs = append(errs, fmt.Errorf("%s: union %s has no members", definition.Position, definition.Name))
+			}
+		}
+
+		fieldNames := map[string]bool{}
+		for _, field := range definition.Fields {
+			if fieldNames[field.Name] {
+				errs = append(errs, fmt.Errorf("%s: field %s.%s is defined more than once", field.Position, definition.Name, field.Name))
+			}
+			fieldNames[field.Name] = true
+
+			if named := field.Type.NamedType(); named == "" {
+				errs = append(errs, fmt.Errorf("%s: field %s.%s has no type", field.Position, definition.Name, field.Name))
+			} else if !defined[named] {
+				errs = append(errs, fmt.Errorf("%s: field %s.%s has undefined type %s", field.Position, definition.Name, field.Name, named))
+			}
+			for _, argument := range field.Arguments {
+				if named := argument.Type.NamedType(); !defined[named] {
+					errs = append(errs, fmt.Errorf("%s: argument %s of %s.%s has undefined type %s", field.Position, argument.Name, definition.Name, field.Name, named))
+				}
+			}
+		}
+
+		for _, name := range definition.Interfaces {
+			if !defined[name] {
+				errs = append(errs, fmt.Errorf("%s: %s implements undefined interface %s", definition.Position, definition.Name, name))
+			}
+		}
+		for _, member := range definition.UnionMembers {
+			if !defined[member] {
+				errs = append(errs, fmt.Errorf("%s: union %s has undefined member %s", definition.Position, definition.Name, member))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParseAndValidate parses an SDL document and validates it, see Schema.Validate
+func ParseAndValidate(source string, knownTypes ...string) (*Schema, error) {
+	schema, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.Validate(knownTypes...); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
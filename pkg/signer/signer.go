@@ -12,6 +12,7 @@ import (
 
 	libp2pcrypto "github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/shinzonetwork/app-sdk/pkg/config"
+	"github.com/shinzonetwork/app-sdk/pkg/defra"
 	"github.com/sourcenetwork/defradb/acp/identity"
 	"github.com/sourcenetwork/defradb/crypto"
 	"github.com/sourcenetwork/defradb/keyring"
@@ -194,22 +195,61 @@ func getStorePath(defraNode *node.Node, cfg *config.Config) (string, error) {
 	return "", fmt.Errorf("could not find defra_identity.key in any common location. Please ensure the key file exists or provide a store path in config")
 }
 
-// SignWithDefraKeys signs a message using the DefraDB identity's private key (secp256k1).
-// The signature is returned as a hex-encoded string.
+// LoadIdentity loads the DefraDB identity used by the node, so that it can be reused across signing calls
+// instead of being re-read from storage each time.
 // If cfg is provided and has a KeyringSecret, it will use the keyring; otherwise falls back to file-based storage.
-func SignWithDefraKeys(message string, defraNode *node.Node, cfg *config.Config) (string, error) {
+// In-memory nodes keep their identity on the defra.Node that started them, so use LoadNodeIdentity for those. A remote
+// DefraDB's identity lives with the remote process, so loading it fails with defra.ErrNotSupportedRemotely rather than
+// picking up a local key.
+func LoadIdentity(defraNode *node.Node, cfg *config.Config) (identity.FullIdentity, error) {
+	if defra.IsRemote(defraNode) {
+		return nil, fmt.Errorf("failed to load identity: %w", defra.ErrNotSupportedRemotely)
+	}
+	if cfg != nil && cfg.DefraDB.Store.InMemory {
+		return nil, fmt.Errorf("failed to load identity: an in-memory node's identity is only held by its defra.Node, see LoadNodeIdentity")
+	}
+
 	// Get the store path
 	storePath, err := getStorePath(defraNode, cfg)
 	if err != nil {
-		return "", fmt.Errorf("failed to get store path: %w", err)
+		return nil, fmt.Errorf("failed to get store path: %w", err)
 	}
 
 	// Load the identity from storage (tries keyring first, then file)
 	fullIdentity, err := loadIdentityFromStore(cfg, storePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to load identity: %w", err)
+		return nil, fmt.Errorf("failed to load identity: %w", err)
 	}
+	return fullIdentity, nil
+}
+
+// LoadNodeIdentity is LoadIdentity for a node started by defra.StartNode, which also finds the identity of an in-memory
+// node in the keyring it holds
+func LoadNodeIdentity(defraNode *defra.Node, cfg *config.Config) (identity.FullIdentity, error) {
+	if kr, ok := defraNode.InMemoryKeyring(); ok {
+		fullIdentity, err := loadIdentityFromKeyring(kr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load identity: %w", err)
+		}
+		return fullIdentity, nil
+	}
+	return LoadIdentity(defraNode.Node, cfg)
+}
 
+// SignWithDefraKeys signs a message using the DefraDB identity's private key (secp256k1).
+// The signature is returned as a hex-encoded string.
+// If cfg is provided and has a KeyringSecret, it will use the keyring; otherwise falls back to file-based storage.
+func SignWithDefraKeys(message string, defraNode *node.Node, cfg *config.Config) (string, error) {
+	fullIdentity, err := LoadIdentity(defraNode, cfg)
+	if err != nil {
+		return "", err
+	}
+	return SignWithIdentity(message, fullIdentity)
+}
+
+// SignWithIdentity signs a message using the given identity's private key (secp256k1).
+// The signature is returned as a hex-encoded string.
+func SignWithIdentity(message string, fullIdentity identity.FullIdentity) (string, error) {
 	// Get the private key
 	privateKey := fullIdentity.PrivateKey()
 	if privateKey == nil {
@@ -230,18 +270,16 @@ func SignWithDefraKeys(message string, defraNode *node.Node, cfg *config.Config)
 // The signature is returned as a hex-encoded string.
 // If cfg is provided and has a KeyringSecret, it will use the keyring; otherwise falls back to file-based storage.
 func SignWithP2PKeys(message string, defraNode *node.Node, cfg *config.Config) (string, error) {
-	// Get the store path
-	storePath, err := getStorePath(defraNode, cfg)
+	fullIdentity, err := LoadIdentity(defraNode, cfg)
 	if err != nil {
-		return "", fmt.Errorf("failed to get store path: %w", err)
-	}
-
-	// Load the identity from storage (tries keyring first, then file)
-	fullIdentity, err := loadIdentityFromStore(cfg, storePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to load identity: %w", err)
+		return "", err
 	}
+	return SignWithP2PIdentity(message, fullIdentity)
+}
 
+// SignWithP2PIdentity signs a message using the LibP2P private key (Ed25519) derived from the given identity.
+// The signature is returned as a hex-encoded string.
+func SignWithP2PIdentity(message string, fullIdentity identity.FullIdentity) (string, error) {
 	// Create LibP2P private key from the identity
 	libp2pPrivKey, err := createLibP2PKeyFromIdentity(fullIdentity)
 	if err != nil {
@@ -279,18 +317,15 @@ func SignWithP2PKeys(message string, defraNode *node.Node, cfg *config.Config) (
 // GetDefraPublicKey returns the DefraDB identity's public key as a hex-encoded string.
 // If cfg is provided and has a KeyringSecret, it will use the keyring; otherwise falls back to file-based storage.
 func GetDefraPublicKey(defraNode *node.Node, cfg *config.Config) (string, error) {
-	// Get the store path
-	storePath, err := getStorePath(defraNode, cfg)
+	fullIdentity, err := LoadIdentity(defraNode, cfg)
 	if err != nil {
-		return "", fmt.Errorf("failed to get store path: %w", err)
-	}
-
-	// Load the identity from storage (tries keyring first, then file)
-	fullIdentity, err := loadIdentityFromStore(cfg, storePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to load identity: %w", err)
+		return "", err
 	}
+	return GetIdentityPublicKey(fullIdentity)
+}
 
+// GetIdentityPublicKey returns the given identity's public key (secp256k1) as a hex-encoded string.
+func GetIdentityPublicKey(fullIdentity identity.FullIdentity) (string, error) {
 	// Get the public key
 	publicKey := fullIdentity.PublicKey()
 	if publicKey == nil {
@@ -304,18 +339,15 @@ func GetDefraPublicKey(defraNode *node.Node, cfg *config.Config) (string, error)
 // GetP2PPublicKey returns the LibP2P public key (Ed25519) derived from the DefraDB identity as a hex-encoded string.
 // If cfg is provided and has a KeyringSecret, it will use the keyring; otherwise falls back to file-based storage.
 func GetP2PPublicKey(defraNode *node.Node, cfg *config.Config) (string, error) {
-	// Get the store path
-	storePath, err := getStorePath(defraNode, cfg)
+	fullIdentity, err := LoadIdentity(defraNode, cfg)
 	if err != nil {
-		return "", fmt.Errorf("failed to get store path: %w", err)
-	}
-
-	// Load the identity from storage (tries keyring first, then file)
-	fullIdentity, err := loadIdentityFromStore(cfg, storePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to load identity: %w", err)
+		return "", err
 	}
+	return GetIdentityP2PPublicKey(fullIdentity)
+}
 
+// GetIdentityP2PPublicKey returns the LibP2P public key (Ed25519) derived from the given identity as a hex-encoded string.
+func GetIdentityP2PPublicKey(fullIdentity identity.FullIdentity) (string, error) {
 	// Create LibP2P private key from the identity
 	libp2pPrivKey, err := createLibP2PKeyFromIdentity(fullIdentity)
 	if err != nil {
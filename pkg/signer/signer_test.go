@@ -2,6 +2,7 @@ package signer
 
 import (
 	"context"
+	"os"
 	"strings"
 	"testing"
 
@@ -122,6 +123,43 @@ func TestSignAndVerifyDefraSignature(t *testing.T) {
 	require.Error(t, err, "Signature verification should fail with wrong signature")
 }
 
+func TestSignWithInMemoryNode(t *testing.T) {
+	storePath := t.TempDir()
+	testConfig := &config.Config{
+		DefraDB: config.DefraDBConfig{
+			Url: "http://localhost:0",
+			P2P: config.DefraP2PConfig{
+				ListenAddr: "/ip4/127.0.0.1/tcp/0",
+			},
+			Store: config.DefraStoreConfig{
+				Path:     storePath,
+				InMemory: true,
+			},
+		},
+	}
+	defraNode, err := defra.StartNode(context.Background(), testConfig, &defra.MockSchemaApplierThatSucceeds{})
+	require.NoError(t, err)
+	defer defraNode.Close(context.Background())
+
+	message := "Test message for an in-memory node"
+	fullIdentity, err := LoadNodeIdentity(defraNode, testConfig)
+	require.NoError(t, err)
+	signature, err := SignWithIdentity(message, fullIdentity)
+	require.NoError(t, err)
+	publicKey, err := GetIdentityPublicKey(fullIdentity)
+	require.NoError(t, err)
+	require.NoError(t, VerifyDefraSignature(publicKey, message, signature))
+
+	// The bare DefraDB node doesn't carry the keyring, so its identity can't be found from it
+	_, err = SignWithDefraKeys(message, defraNode.Node, testConfig)
+	require.Error(t, err)
+
+	// Neither the identity nor the store were written to disk
+	entries, err := os.ReadDir(storePath)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
 func TestSignAndVerifyP2PSignature(t *testing.T) {
 	defraNode, cfg := setupTestNode(t)
 	defer defraNode.Close(context.Background())
@@ -402,3 +440,20 @@ func TestSignWithP2PKeys_LongMessage(t *testing.T) {
 	err = VerifyP2PSignature(publicKey, message, signature)
 	require.NoError(t, err)
 }
+
+func TestSignWithRemoteNode(t *testing.T) {
+	defraNode, cfg := setupTestNode(t)
+	defer defraNode.Close(context.Background())
+	remote, err := defra.ConnectToRemote(context.Background(), defraNode.APIURL)
+	require.NoError(t, err)
+
+	// The local keyring in cfg isn't the remote DefraDB's, so it must not be used to sign on its behalf
+	_, err = LoadIdentity(remote, cfg)
+	require.ErrorIs(t, err, defra.ErrNotSupportedRemotely)
+	_, err = SignWithDefraKeys("Hello, World!", remote, cfg)
+	require.ErrorIs(t, err, defra.ErrNotSupportedRemotely)
+	_, err = SignWithP2PKeys("Hello, World!", remote, cfg)
+	require.ErrorIs(t, err, defra.ErrNotSupportedRemotely)
+	_, err = GetDefraPublicKey(remote, cfg)
+	require.ErrorIs(t, err, defra.ErrNotSupportedRemotely)
+}
@@ -38,10 +38,25 @@ func TestGetAttestationRecords(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, records)
 	require.Len(t, records, 2)
-	for _, record := range records {
-		if record.AttestedDocId != "ArbitraryDocId: 1" && record.AttestedDocId != "ArbitraryDocId: 7" {
-			t.Fatalf("Encountered unexpected AttestedDocId: %s", record.AttestedDocId)
+	for docId, docRecords := range records {
+		if docId != "ArbitraryDocId: 1" && docId != "ArbitraryDocId: 7" {
+			t.Fatalf("Encountered unexpected AttestedDocId: %s", docId)
 		}
-		require.Len(t, record.CIDs, 2)
+		require.Len(t, docRecords, 1)
+		require.Equal(t, docId, docRecords[0].AttestedDocId)
+		require.Len(t, docRecords[0].CIDs, 2)
+	}
+
+	// Small batches fanned out concurrently return the same records, and docs without attestations are reported as empty rather than as an error
+	docIds := []string{"ArbitraryDocId: 11"}
+	for i := 0; i < 10; i++ {
+		docIds = append(docIds, fmt.Sprintf("ArbitraryDocId: %d", i+1))
+	}
+	records, err = attestation.GetAttestationRecords(ctx, defraNode, "SampleView", docIds, attestation.WithBatchSize(3), attestation.WithConcurrency(2))
+	require.NoError(t, err)
+	require.Len(t, records, 11)
+	require.Empty(t, records["ArbitraryDocId: 11"])
+	for i := 0; i < 10; i++ {
+		require.Len(t, records[fmt.Sprintf("ArbitraryDocId: %d", i+1)], 1)
 	}
 }
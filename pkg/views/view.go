@@ -5,12 +5,25 @@ import (
 	"fmt"
 
 	"github.com/shinzonetwork/app-sdk/pkg/defra"
+	"github.com/shinzonetwork/app-sdk/pkg/sdl"
 	"github.com/shinzonetwork/view-creator/core/models"
 	"github.com/sourcenetwork/defradb/node"
 )
 
 type View models.View
 
+// ParseSdl parses the view's SDL, for inspecting the types it defines
+func (view *View) ParseSdl() (*sdl.Schema, error) {
+	if view.Sdl == nil || *view.Sdl == "" {
+		return nil, fmt.Errorf("View %s has no SDL", view.Name)
+	}
+	schema, err := sdl.Parse(*view.Sdl)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing view's SDL: %w", err)
+	}
+	return schema, nil
+}
+
 func (view *View) SubscribeTo(ctx context.Context, defraNode *node.Node) error {
 	schemaApplier := defra.NewSchemaApplierFromProvidedSchema(*view.Sdl)
 	err := schemaApplier.ApplySchema(ctx, defraNode)
@@ -40,3 +40,36 @@ func TestSubscribeToInvalidViewFails(t *testing.T) {
 	err = testView.SubscribeTo(context.Background(), myDefra)
 	require.Error(t, err)
 }
+
+func TestParseSdl(t *testing.T) {
+	sdl := "# A comment\ntype FilteredAndDecodedLogs @materialized(if: false) {transactionHash: String}"
+	testView := View{Sdl: &sdl, Name: "FilteredAndDecodedLogs"}
+	schema, err := testView.ParseSdl()
+	require.NoError(t, err)
+	require.Equal(t, []string{"FilteredAndDecodedLogs"}, schema.TypeNames())
+
+	invalid := "type FilteredAndDecodedLogs {transactionHash: String"
+	testView.Sdl = &invalid
+	_, err = testView.ParseSdl()
+	require.Error(t, err)
+
+	testView.Sdl = nil
+	_, err = testView.ParseSdl()
+	require.Error(t, err)
+}
+
+func TestSubscribeToViewValidatesSdl(t *testing.T) {
+	query := "Log {address topics data transactionHash blockNumber}"
+	sdl := "type FilteredAndDecodedLogs {transactionHash: String\n log: UndefinedLog}"
+	testView := View{
+		Query:     &query,
+		Sdl:       &sdl,
+		Transform: models.Transform{},
+		Name:      "FilteredAndDecodedLogs",
+	}
+
+	myDefra, err := defra.StartDefraInstanceWithTestConfig(t, defra.DefaultConfig, &defra.MockSchemaApplierThatSucceeds{})
+	require.NoError(t, err)
+	err = testView.SubscribeTo(context.Background(), myDefra)
+	require.ErrorContains(t, err, "2:2: field FilteredAndDecodedLogs.log has undefined type UndefinedLog")
+}